@@ -1,7 +1,6 @@
 package staleness
 
 import (
-	"context"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -9,31 +8,32 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
 )
 
 var checkName string = "staleness"
 
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/backup/staleness",
+		Description: "Checks for Velero backups to ensure there are successful fresh backups.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{BackupNamespace: "velero"}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+	})
+}
+
 // QuerierSpec defines the Specification for a Querier.
 type QuerierSpec struct {
-	BackupNamespace string        `yaml:"backup_namespace"`
-	MaxAge          time.Duration `yaml:"max_age"`
+	BackupNamespace string        `yaml:"backup_namespace" description:"The Namespace to query for backups."`
+	MaxAge          time.Duration `yaml:"max_age" description:"Reserved for the maximum age of the most recent backup."`
 }
 
 // Querier defines the query and set of checks.
 type Querier struct {
-	client dynamic.Interface
-	Spec   *QuerierSpec `yaml:"spec"`
-}
-
-// AddtoRunner configures a runner with the Querier for this check.
-func (querier *Querier) AddtoRunner(runner *internal.Runner) {
-	runner.Queriers = append(runner.Queriers, querier)
-	runner.Logger.WithFields(log.Fields{
-		"check_name": checkName,
-		"phase":      "add",
-	}).Info("complete")
+	Spec *QuerierSpec `yaml:"spec"`
 }
 
 // NewQuerier returns a new configured Querier.
@@ -41,15 +41,6 @@ func NewQuerier(spec *QuerierSpec) (Querier, error) {
 	out := Querier{
 		Spec: spec,
 	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return out, err
-	}
-	client, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return out, err
-	}
-	out.client = client
 	return out, nil
 }
 
@@ -67,7 +58,7 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 	}
 	backupGVR := schema.GroupVersionResource{Group: "velero.io", Version: "v1", Resource: "backups"}
 
-	backups, err := q.client.Resource(backupGVR).Namespace(q.Spec.BackupNamespace).List(context.TODO(), metav1.ListOptions{})
+	backups, err := cfg.DynamicClient.Resource(backupGVR).Namespace(q.Spec.BackupNamespace).List(cfg.Context, metav1.ListOptions{})
 	if err != nil {
 		cfg.Logger.WithFields(log.Fields{
 			"check_name": checkName,
@@ -76,9 +67,10 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		details := make(map[string]interface{})
 		details["error"] = "no backups defined"
 		item := internal.Item{
-			Name:    q.Spec.BackupNamespace,
-			Status:  "failed",
-			Details: details,
+			Name:      q.Spec.BackupNamespace,
+			Namespace: q.Spec.BackupNamespace,
+			Status:    "failed",
+			Details:   details,
 		}
 		checkItem.Items = append(checkItem.Items, item)
 		cfg.Results <- checkItem
@@ -94,8 +86,9 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		if len(backups.Items) > 0 {
 			for _, backup := range backups.Items {
 				item := internal.Item{
-					Name:   backup.GetName(),
-					Status: "failed",
+					Name:      backup.GetName(),
+					Namespace: backup.GetNamespace(),
+					Status:    "failed",
 				}
 
 				details := make(map[string]interface{})
@@ -0,0 +1,65 @@
+package staleness
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func backup(namespace, name string, status map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "velero.io/v1",
+		"kind":       "Backup",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"status":     status,
+	}}
+}
+
+func TestStaleness(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-24 * time.Hour).Format(time.RFC3339)
+	objects := []runtime.Object{
+		backup("velero", "fresh", map[string]interface{}{"phase": "Completed", "expiration": future}),
+		backup("velero", "expired", map[string]interface{}{"phase": "Completed", "expiration": past}),
+		backup("velero", "partial", map[string]interface{}{"phase": "PartiallyFailed", "expiration": future}),
+		backup("velero", "in-progress", map[string]interface{}{"phase": "InProgress"}),
+		backup("backups", "other", map[string]interface{}{"phase": "Completed", "expiration": future}),
+	}
+	testcases := []struct {
+		desc string
+		spec map[string]interface{}
+		want map[string]string
+	}{
+		{
+			desc: "Backups must be completed and unexpired",
+			want: map[string]string{"fresh": "passed", "expired": "failed", "partial": "failed"},
+		}, {
+			desc: "The backup namespace is configurable",
+			spec: map[string]interface{}{"backup_namespace": "backups"},
+			want: map[string]string{"other": "passed"},
+		}, {
+			desc: "Namespaces without backups pass",
+			spec: map[string]interface{}{"backup_namespace": "empty"},
+			want: map[string]string{},
+		},
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "velero.io", Version: "v1", Resource: "backups"}: "BackupList",
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := internal.CheckConfig{Kind: "v1alpha1/backup/staleness", Spec: tc.spec}
+			item := checktest.Run(t, cfg, checktest.Cluster{Dynamic: objects, ListKinds: listKinds})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package certificates
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var checkName string = "certificates"
+
+var apiServiceGVR = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+
+// defaultWarnWithinDays is the warning window when it is not configured, unless the failure window is longer.
+const defaultWarnWithinDays = 30
+
+// dialTimeout bounds connecting to the API server to read its serving certificate.
+var dialTimeout = 10 * time.Second
+
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/cluster/certificates",
+		Description: "Checks TLS Secrets, webhook and APIService CA bundles and the API server's serving certificate for expiry.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{
+				FailWithinDays:   7,
+				TLSSecrets:       true,
+				Webhooks:         true,
+				APIServices:      true,
+				APIServerServing: true,
+			}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+	})
+}
+
+// QuerierSpec defines the Specification for a Querier.
+type QuerierSpec struct {
+	WarnWithinDays   *int `yaml:"warn_within_days" description:"Warn about certificates which expire within this many days. Defaults to 30, or fail_within_days if it is greater."`
+	FailWithinDays   int  `yaml:"fail_within_days" description:"Fail certificates which expire within this many days."`
+	TLSSecrets       bool `yaml:"tls_secrets" description:"Check the certificates of kubernetes.io/tls Secrets."`
+	Webhooks         bool `yaml:"webhooks" description:"Check the caBundle of admission webhooks."`
+	APIServices      bool `yaml:"api_services" description:"Check the caBundle of APIServices."`
+	APIServerServing bool `yaml:"api_server" description:"Check the API server's serving certificate."`
+}
+
+// Validate checks that the windows are ordered.
+func (spec *QuerierSpec) Validate() error {
+	if spec.FailWithinDays < 0 {
+		return fmt.Errorf("fail_within_days must not be negative, got %d", spec.FailWithinDays)
+	}
+	if spec.WarnWithinDays != nil && *spec.WarnWithinDays < spec.FailWithinDays {
+		return fmt.Errorf("warn_within_days (%d) must not be less than fail_within_days (%d)", *spec.WarnWithinDays, spec.FailWithinDays)
+	}
+	return nil
+}
+
+// warnWithinDays returns the configured warning window, defaulting to at least the failure window.
+func (spec *QuerierSpec) warnWithinDays() int {
+	if spec.WarnWithinDays != nil {
+		return *spec.WarnWithinDays
+	}
+	if spec.FailWithinDays > defaultWarnWithinDays {
+		return spec.FailWithinDays
+	}
+	return defaultWarnWithinDays
+}
+
+// Querier defines the query and set of checks.
+type Querier struct {
+	Spec *QuerierSpec `yaml:"spec"`
+}
+
+// NewQuerier returns a new configured Querier.
+func NewQuerier(spec *QuerierSpec) (Querier, error) {
+	out := Querier{
+		Spec: spec,
+	}
+	return out, nil
+}
+
+// Start runs the Querier.
+func (q Querier) Start(cfg *internal.QuerierConfig) {
+	cfg.Logger.WithFields(log.Fields{
+		"check_name": checkName,
+		"phase":      "add",
+	}).Info(internal.CheckStartMsg)
+
+	checkItem := internal.ReportItem{
+		Name:   checkName,
+		Status: "passed",
+	}
+
+	sources := []struct {
+		enabled bool
+		items   func(*internal.QuerierConfig, *internal.ReportItem) ([]internal.Item, error)
+	}{
+		{q.Spec.TLSSecrets, q.secretItems},
+		{q.Spec.Webhooks, q.webhookItems},
+		{q.Spec.APIServices, q.apiServiceItems},
+		{q.Spec.APIServerServing, q.apiServerItems},
+	}
+	for _, source := range sources {
+		if !source.enabled {
+			continue
+		}
+		items, err := source.items(cfg, &checkItem)
+		if err != nil {
+			cfg.Logger.WithFields(log.Fields{
+				"check_name": checkName,
+				"phase":      "run",
+			}).Error(err)
+			checkItem.Status = "failed"
+		}
+		checkItem.Items = append(checkItem.Items, items...)
+	}
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "complete",
+	}).Info(internal.CheckCompleteMsg)
+
+	cfg.Results <- checkItem
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "write",
+	}).Info(internal.CheckWriteMsg)
+}
+
+// secretItems returns an Item for each kubernetes.io/tls Secret in scope.
+func (q Querier) secretItems(cfg *internal.QuerierConfig, checkItem *internal.ReportItem) ([]internal.Item, error) {
+	secrets, err := cfg.Client.CoreV1().Secrets("").List(cfg.Context, metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeTLS),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list TLS secrets: %v", err)
+	}
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		a, b := secrets.Items[i], secrets.Items[j]
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+
+	var items []internal.Item
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !cfg.Scope.InNamespace(secret.Namespace) {
+			continue
+		}
+		name := fmt.Sprintf("%s/secret/%s", secret.Namespace, secret.Name)
+		if exemption, ok := cfg.Scope.Exempt(name, "Secret", secret); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+		item := q.bundleItem(name, secret.Data[corev1.TLSCertKey])
+		item.Namespace = secret.Namespace
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// webhookItems returns an Item for each admission webhook which sets a caBundle.
+func (q Querier) webhookItems(cfg *internal.QuerierConfig, checkItem *internal.ReportItem) ([]internal.Item, error) {
+	var items []internal.Item
+	validating, err := cfg.Client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(cfg.Context, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list validating webhook configurations: %v", err)
+	}
+	for i := range validating.Items {
+		config := &validating.Items[i]
+		name := fmt.Sprintf("validatingwebhookconfiguration/%s", config.Name)
+		if exemption, ok := cfg.Scope.Exempt(name, "ValidatingWebhookConfiguration", config); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+		for _, webhook := range config.Webhooks {
+			if len(webhook.ClientConfig.CABundle) > 0 {
+				items = append(items, q.bundleItem(name+"/"+webhook.Name, webhook.ClientConfig.CABundle))
+			}
+		}
+	}
+
+	mutating, err := cfg.Client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(cfg.Context, metav1.ListOptions{})
+	if err != nil {
+		return items, fmt.Errorf("unable to list mutating webhook configurations: %v", err)
+	}
+	for i := range mutating.Items {
+		config := &mutating.Items[i]
+		name := fmt.Sprintf("mutatingwebhookconfiguration/%s", config.Name)
+		if exemption, ok := cfg.Scope.Exempt(name, "MutatingWebhookConfiguration", config); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+		for _, webhook := range config.Webhooks {
+			if len(webhook.ClientConfig.CABundle) > 0 {
+				items = append(items, q.bundleItem(name+"/"+webhook.Name, webhook.ClientConfig.CABundle))
+			}
+		}
+	}
+	return items, nil
+}
+
+// apiServiceItems returns an Item for each APIService which sets a caBundle. APIServices served
+// by the API server itself have none.
+func (q Querier) apiServiceItems(cfg *internal.QuerierConfig, checkItem *internal.ReportItem) ([]internal.Item, error) {
+	apiServices, err := cfg.DynamicClient.Resource(apiServiceGVR).List(cfg.Context, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list APIServices: %v", err)
+	}
+
+	var items []internal.Item
+	for i := range apiServices.Items {
+		apiService := &apiServices.Items[i]
+		encoded, found, err := unstructured.NestedString(apiService.Object, "spec", "caBundle")
+		if err != nil || !found || encoded == "" {
+			continue
+		}
+		name := fmt.Sprintf("apiservice/%s", apiService.GetName())
+		if exemption, ok := cfg.Scope.Exempt(name, "APIService", apiService); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+		bundle, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			items = append(items, failedItem(name, fmt.Sprintf("caBundle is not valid base64: %v", err)))
+			continue
+		}
+		items = append(items, q.bundleItem(name, bundle))
+	}
+	return items, nil
+}
+
+// apiServerItems returns an Item for the certificate the API server serves.
+func (q Querier) apiServerItems(cfg *internal.QuerierConfig, checkItem *internal.ReportItem) ([]internal.Item, error) {
+	if cfg.RestConfig == nil {
+		return nil, nil
+	}
+	host, err := hostPort(cfg.RestConfig.Host)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return nil, nil
+	}
+	name := fmt.Sprintf("apiserver/%s", host)
+
+	ctx, cancel := context.WithTimeout(cfg.Context, dialTimeout)
+	defer cancel()
+	dialer := &tls.Dialer{Config: &tls.Config{
+		// The certificate is read rather than verified, so that an expired certificate is reported.
+		InsecureSkipVerify: true,
+		ServerName:         cfg.RestConfig.TLSClientConfig.ServerName,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return []internal.Item{failedItem(name, fmt.Sprintf("unable to connect: %v", err))}, nil
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return []internal.Item{failedItem(name, "no certificate was served")}, nil
+	}
+	return []internal.Item{q.certificateItem(name, state.PeerCertificates[0])}, nil
+}
+
+// hostPort returns the host and port of an https API server URL, or an empty string for
+// plain http.
+func hostPort(host string) (string, error) {
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		// Hosts may be given without a scheme, e.g. 10.0.0.1:6443.
+		u, err = url.Parse("https://" + host)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse API server host %q: %v", host, err)
+		}
+	}
+	if u.Scheme != "https" {
+		return "", nil
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	}
+	return u.Host, nil
+}
+
+// bundleItem returns an Item for the PEM bundle, which is judged by the certificate that expires first.
+func (q Querier) bundleItem(name string, bundle []byte) internal.Item {
+	var certs []*x509.Certificate
+	for rest := bundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return failedItem(name, fmt.Sprintf("unable to parse certificate: %v", err))
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return failedItem(name, "no certificates found")
+	}
+
+	first := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+	item := q.certificateItem(name, first)
+	item.Details["certificates"] = len(certs)
+	return item
+}
+
+// certificateItem returns an Item for the certificate, which fails or warns if it expires within
+// the configured windows.
+func (q Querier) certificateItem(name string, cert *x509.Certificate) internal.Item {
+	remaining := time.Until(cert.NotAfter)
+	days := int(remaining.Hours() / 24)
+	subject := cert.Subject.CommonName
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+	item := internal.Item{
+		Name:   name,
+		Status: "passed",
+		Details: map[string]interface{}{
+			"subject":        subject,
+			"not_after":      cert.NotAfter.UTC().Format(time.RFC3339),
+			"days_remaining": days,
+		},
+	}
+	switch {
+	case remaining <= 0:
+		item.Status = "failed"
+		item.Details["error"] = "certificate has expired"
+	case remaining <= daysToDuration(q.Spec.FailWithinDays):
+		item.Status = "failed"
+		item.Details["error"] = fmt.Sprintf("certificate expires within %d days", q.Spec.FailWithinDays)
+	case remaining <= daysToDuration(q.Spec.warnWithinDays()):
+		item.Status = internal.StatusWarning
+		item.Details["warning"] = fmt.Sprintf("certificate expires within %d days", q.Spec.warnWithinDays())
+	}
+	return item
+}
+
+func daysToDuration(days int) time.Duration {
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func failedItem(name, err string) internal.Item {
+	return internal.Item{
+		Name:   name,
+		Status: "failed",
+		Details: map[string]interface{}{
+			"error": err,
+		},
+	}
+}
@@ -0,0 +1,188 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+const day = 24 * time.Hour
+
+// certPEM returns a self-signed certificate which expires after d.
+func certPEM(t *testing.T, cn string, d time.Duration) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(d),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func tlsSecret(name string, crt []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: crt},
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	// The check closes its connection once it has the certificate, which the server would log.
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	testcases := []struct {
+		desc    string
+		spec    map[string]interface{}
+		objects []runtime.Object
+		dynamic []runtime.Object
+		want    map[string]string
+	}{
+		{
+			desc: "TLS Secrets are checked against the windows",
+			spec: map[string]interface{}{"webhooks": false, "api_services": false, "api_server": false},
+			objects: []runtime.Object{
+				tlsSecret("fresh", certPEM(t, "fresh", 100*day)),
+				tlsSecret("soon", append(certPEM(t, "soon", 20*day), certPEM(t, "ca", 400*day)...)),
+				tlsSecret("expiring", certPEM(t, "expiring", 3*day)),
+				tlsSecret("invalid", []byte("not a certificate")),
+			},
+			want: map[string]string{
+				"app/secret/fresh":    "passed",
+				"app/secret/soon":     "warning",
+				"app/secret/expiring": "failed",
+				"app/secret/invalid":  "failed",
+			},
+		}, {
+			desc: "The warning window defaults to at least the failure window",
+			spec: map[string]interface{}{"fail_within_days": 45, "webhooks": false, "api_services": false, "api_server": false},
+			objects: []runtime.Object{
+				tlsSecret("fresh", certPEM(t, "fresh", 100*day)),
+				tlsSecret("soon", certPEM(t, "soon", 40*day)),
+			},
+			want: map[string]string{
+				"app/secret/fresh": "passed",
+				"app/secret/soon":  "failed",
+			},
+		}, {
+			desc: "Webhook and APIService CA bundles are checked",
+			spec: map[string]interface{}{"tls_secrets": false, "api_server": false},
+			objects: []runtime.Object{
+				&admissionv1.ValidatingWebhookConfiguration{
+					ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+					Webhooks: []admissionv1.ValidatingWebhook{
+						{Name: "validate.example.com", ClientConfig: admissionv1.WebhookClientConfig{CABundle: certPEM(t, "ca", 3*day)}},
+						{Name: "unbundled.example.com"},
+					},
+				},
+			},
+			dynamic: []runtime.Object{
+				&unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "apiregistration.k8s.io/v1",
+					"kind":       "APIService",
+					"metadata":   map[string]interface{}{"name": "v1beta1.metrics.k8s.io"},
+					"spec":       map[string]interface{}{"caBundle": base64.StdEncoding.EncodeToString(certPEM(t, "ca", 100*day))},
+				}},
+			},
+			want: map[string]string{
+				"validatingwebhookconfiguration/policy/validate.example.com": "failed",
+				"apiservice/v1beta1.metrics.k8s.io":                          "passed",
+			},
+		}, {
+			desc: "The API server's serving certificate is checked",
+			spec: map[string]interface{}{"tls_secrets": false, "webhooks": false, "api_services": false},
+			want: map[string]string{"apiserver/" + strings.TrimPrefix(srv.URL, "https://"): "passed"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			item := checktest.Run(t, internal.CheckConfig{Kind: "v1alpha1/cluster/certificates", Spec: tc.spec}, checktest.Cluster{
+				Objects:    tc.objects,
+				Dynamic:    tc.dynamic,
+				ListKinds:  map[schema.GroupVersionResource]string{apiServiceGVR: "APIServiceList"},
+				RestConfig: &rest.Config{Host: srv.URL},
+			})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSpecWindows(t *testing.T) {
+	testcases := []struct {
+		desc      string
+		spec      map[string]interface{}
+		expectErr string
+		wantWarn  int
+	}{
+		{desc: "Defaults", wantWarn: 30},
+		{desc: "Only fail_within_days longer than the default warning window", spec: map[string]interface{}{"fail_within_days": 45}, wantWarn: 45},
+		{desc: "Only fail_within_days shorter than the default warning window", spec: map[string]interface{}{"fail_within_days": 14}, wantWarn: 30},
+		{desc: "Both windows", spec: map[string]interface{}{"warn_within_days": 60, "fail_within_days": 45}, wantWarn: 60},
+		{
+			desc:      "Warning window shorter than the failure window",
+			spec:      map[string]interface{}{"warn_within_days": 10, "fail_within_days": 14},
+			expectErr: "invalid spec: warn_within_days (10) must not be less than fail_within_days (14)",
+		}, {
+			desc:      "Negative failure window",
+			spec:      map[string]interface{}{"fail_within_days": -1},
+			expectErr: "invalid spec: fail_within_days must not be negative, got -1",
+		},
+	}
+
+	def, _ := internal.LookupCheck("v1alpha1/cluster/certificates")
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			spec, err := def.DecodeSpec(tc.spec)
+			switch {
+			case err != nil && len(tc.expectErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.expectErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && err.Error() != tc.expectErr:
+				t.Fatalf("Expected error %q but got %q", tc.expectErr, err.Error())
+			case err != nil:
+				return
+			}
+			if got := spec.(*QuerierSpec).warnWithinDays(); got != tc.wantWarn {
+				t.Errorf("Expected a warning window of %d days but got %d", tc.wantWarn, got)
+			}
+		})
+	}
+}
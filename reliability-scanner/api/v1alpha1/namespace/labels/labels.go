@@ -6,35 +6,40 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var (
 	checkName string = "labels"
 )
 
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/namespace/labels",
+		Description: "Checks Namespaces for an owner label.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{
+				Key:           "owner",
+				IncludeDetail: true,
+			}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourceNamespaces},
+	})
+}
+
 // QuerierSpec defines the Specification for a Querier.
 type QuerierSpec struct {
-	Key           string `yaml:"key"`
-	ValidateURL   bool   `yaml:"validate_url"`
-	IncludeDetail bool   `yaml:"include_detail"`
+	Key           string `yaml:"key" description:"The label which must be set on each Namespace."`
+	ValidateURL   bool   `yaml:"validate_url" description:"Reserved for validating the label value as a URL."`
+	IncludeDetail bool   `yaml:"include_detail" description:"Include the labels configured on each Namespace."`
 }
 
 // Querier defines the query and set of checks.
 type Querier struct {
-	client *kubernetes.Clientset
-	Spec   *QuerierSpec `yaml:"spec"`
-}
-
-// AddtoRunner configures a runner with the Querier for this check.
-func (querier *Querier) AddtoRunner(runner *internal.Runner) {
-	runner.Queriers = append(runner.Queriers, querier)
-	runner.Logger.WithFields(log.Fields{
-		"check_name": checkName,
-		"phase":      "add",
-	}).Info("complete")
+	Spec *QuerierSpec `yaml:"spec"`
 }
 
 // NewQuerier returns a new Querier.
@@ -42,14 +47,6 @@ func NewQuerier(spec *QuerierSpec) (Querier, error) {
 	out := Querier{
 		Spec: spec,
 	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return out, err
-	}
-	out.client, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return out, err
-	}
 	return out, nil
 }
 
@@ -65,12 +62,18 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		Status: "passed",
 	}
 
-	namespaces, err := q.client.CoreV1().Namespaces().List(cfg.Context, metav1.ListOptions{})
+	namespaces, err := cfg.Cache.Namespaces.List(labels.Everything())
 	if err != nil {
 		checkItem.Status = "failed"
 	}
+	namespaces = cfg.Scope.Namespaces(namespaces)
+	internal.SortNamespaces(namespaces)
 
-	for _, namespace := range namespaces.Items {
+	for _, namespace := range namespaces {
+		if exemption, ok := cfg.Scope.Exempt(namespace.Name, "Namespace", namespace); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
 
 		details := make(map[string]interface{})
 		cfg.Logger.WithFields(log.Fields{
@@ -87,14 +90,14 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		}
 
 		item := internal.Item{
-			Name:    namespace.ObjectMeta.Name,
-			Status:  "passed",
-			Details: details,
+			Name:      namespace.ObjectMeta.Name,
+			Namespace: namespace.ObjectMeta.Name,
+			Status:    "passed",
+			Details:   details,
 		}
 
 		if strings.TrimSpace(q.Spec.Key) != "" {
 			if _, exists := namespace.ObjectMeta.Labels[q.Spec.Key]; !exists {
-				checkItem.Status = "failed"
 				item.Status = "failed"
 				item.Details["error"] = fmt.Sprintf("key: %s does not exist", q.Spec.Key)
 			}
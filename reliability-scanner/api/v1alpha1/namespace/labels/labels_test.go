@@ -0,0 +1,65 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func namespace(name string, labels, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+}
+
+func TestLabels(t *testing.T) {
+	objects := []runtime.Object{
+		namespace("owned", map[string]string{"owner": "team-a", "team": "a"}, nil),
+		namespace("team", map[string]string{"team": "b"}, nil),
+		namespace("unlabelled", nil, nil),
+		namespace("sandbox", nil, map[string]string{internal.ExemptAnnotation: "labels"}),
+		namespace("kube-system", nil, nil),
+	}
+	testcases := []struct {
+		desc           string
+		spec           map[string]interface{}
+		want           map[string]string
+		wantExemptions []string
+	}{
+		{
+			desc:           "Namespaces need the owner label by default",
+			want:           map[string]string{"owned": "passed", "team": "failed", "unlabelled": "failed"},
+			wantExemptions: []string{"sandbox"},
+		}, {
+			desc:           "The key is configurable",
+			spec:           map[string]interface{}{"key": "team"},
+			want:           map[string]string{"owned": "passed", "team": "passed", "unlabelled": "failed"},
+			wantExemptions: []string{"sandbox"},
+		}, {
+			desc:           "Every namespace passes without a key",
+			spec:           map[string]interface{}{"key": ""},
+			want:           map[string]string{"owned": "passed", "team": "passed", "unlabelled": "passed"},
+			wantExemptions: []string{"sandbox"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := internal.CheckConfig{Kind: "v1alpha1/namespace/labels", Spec: tc.spec}
+			item := checktest.Run(t, cfg, checktest.Cluster{Objects: objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+			var exemptions []string
+			for _, e := range item.Exemptions {
+				exemptions = append(exemptions, e.Name)
+			}
+			if !reflect.DeepEqual(exemptions, tc.wantExemptions) {
+				t.Errorf("Expected exemptions %v but got %v", tc.wantExemptions, exemptions)
+			}
+		})
+	}
+}
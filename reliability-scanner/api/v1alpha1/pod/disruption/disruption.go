@@ -2,48 +2,51 @@ package disruption
 
 import (
 	"fmt"
+	"sort"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var checkName string = "disruption"
 
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/pod/disruption",
+		Description: "Checks each namespace to see if Pods are covered under a disruption budget.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourcePods, internal.ResourcePodDisruptionBudgets},
+	})
+}
+
 // QuerierSpec defines the Specification for a Querier.
 type QuerierSpec struct {
+	PodDetail bool `yaml:"pod_detail" description:"Include the result of each Pod of a workload."`
+	// IncludeDetail was ignored by the check but set by the shipped plugin configuration, so it is
+	// still accepted to avoid breaking existing configuration.
+	IncludeDetail bool `yaml:"include_detail" description:"Deprecated and ignored, use pod_detail. It will be removed in the next release."`
 }
 
 // Querier defines the query and set of checks.
 type Querier struct {
-	client *kubernetes.Clientset
-	Spec   *QuerierSpec `yaml:"spec"`
-}
-
-// AddtoRunner configures a runner with the Querier for this check.
-func (querier *Querier) AddtoRunner(runner *internal.Runner) {
-	runner.Queriers = append(runner.Queriers, querier)
-	runner.Logger.WithFields(log.Fields{
-		"check_name": checkName,
-		"phase":      "add",
-	}).Info("complete")
+	Spec *QuerierSpec `yaml:"spec"`
 }
 
 // NewQuerier returns a new configured Querier.
 func NewQuerier(spec *QuerierSpec) (Querier, error) {
+	if spec.IncludeDetail {
+		log.WithField("check_name", checkName).Warn("include_detail is deprecated and ignored, use pod_detail instead")
+	}
 	out := Querier{
 		Spec: spec,
 	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return out, err
-	}
-	out.client, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return out, err
-	}
 	return out, nil
 }
 
@@ -59,65 +62,44 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		Status: "passed",
 	}
 
-	namespaces, err := q.client.CoreV1().Namespaces().List(cfg.Context, metav1.ListOptions{})
+	pods, err := cfg.Cache.Pods.List(labels.Everything())
 	if err != nil {
 		checkItem.Status = "failed"
 	}
+	pods = cfg.Scope.Pods(pods)
+	internal.SortPods(pods)
 
-	covered := make(map[string]map[string]bool)
-	pods, err := q.client.CoreV1().Pods("").List(cfg.Context, metav1.ListOptions{})
+	selectors, err := pdbSelectors(cfg.Cache)
 	if err != nil {
+		cfg.Logger.WithFields(log.Fields{
+			"check_name": checkName,
+			"phase":      "run",
+		}).Error(err)
 		checkItem.Status = "failed"
 	}
-	for _, pod := range pods.Items {
-		pods := make(map[string]bool)
-		pods[pod.ObjectMeta.Name] = false
-		covered[pod.ObjectMeta.Namespace] = pods
-	}
 
-	for _, namespace := range namespaces.Items {
-		pdbs, err := q.client.PolicyV1beta1().PodDisruptionBudgets(namespace.Name).List(cfg.Context, metav1.ListOptions{})
-		if err != nil {
-			checkItem.Status = "failed"
+	var findings []internal.PodFinding
+	for _, pod := range pods {
+		if exemption, ok := cfg.Scope.ExemptPod(pod); ok {
+			checkItem.AddExemption(exemption)
+			continue
 		}
-		for _, pdb := range pdbs.Items {
-			pods, err := q.client.CoreV1().Pods(namespace.Name).List(cfg.Context, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(pdb.Spec.Selector)})
-			if err != nil {
-				checkItem.Status = "failed"
-			}
-			for _, pod := range pods.Items {
-				if !internal.IsSonobouyPod(pod.ObjectMeta.Name) {
-					details := make(map[string]interface{})
-					details["managing_disruption_budget"] = pdb.ObjectMeta.Name
-					item := internal.Item{
-						Name:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
-						Status:  "passed",
-						Details: details,
-					}
-					checkItem.Items = append(checkItem.Items, item)
-					covered[namespace.Name][pod.ObjectMeta.Name] = true
-				}
-			}
+		finding := internal.PodFinding{
+			Pod:    pod,
+			Status: "failed",
 		}
-		for ns, pods := range covered {
-			for pod := range pods {
-				if pods[pod] == false {
-					item := internal.Item{
-						Name:   fmt.Sprintf("%s/%s", ns, pod),
-						Status: "failed",
-					}
-					checkItem.Items = append(checkItem.Items, item)
+		for _, pdb := range selectors[pod.Namespace] {
+			if pdb.selector.Matches(labels.Set(pod.Labels)) {
+				finding.Status = "passed"
+				finding.Details = map[string]interface{}{
+					"managing_disruption_budget": pdb.name,
 				}
+				break
 			}
 		}
-
+		findings = append(findings, finding)
 	}
-
-	cfg.Logger.WithFields(log.Fields{
-		"component":  "check",
-		"check_name": checkName,
-		"phase":      "debug",
-	}).Info(covered)
+	checkItem.Items = cfg.Cache.WorkloadItems(findings, q.Spec.PodDetail)
 
 	cfg.Logger.WithFields(log.Fields{
 		"component":  "check",
@@ -133,3 +115,28 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		"phase":      "write",
 	}).Info(internal.CheckWriteMsg)
 }
+
+// budget is a PodDisruptionBudget's name and parsed selector.
+type budget struct {
+	name     string
+	selector labels.Selector
+}
+
+// pdbSelectors returns the selectors of every PodDisruptionBudget, by namespace.
+func pdbSelectors(cache *internal.Cache) (map[string][]budget, error) {
+	pdbs, err := cache.PodDisruptionBudgets.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pdbs, func(i, j int) bool { return pdbs[i].Name < pdbs[j].Name })
+
+	out := make(map[string][]budget)
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return out, fmt.Errorf("disruption budget %s/%s has an invalid selector: %v", pdb.Namespace, pdb.Name, err)
+		}
+		out[pdb.Namespace] = append(out[pdb.Namespace], budget{name: pdb.Name, selector: selector})
+	}
+	return out, nil
+}
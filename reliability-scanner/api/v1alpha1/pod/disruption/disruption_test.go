@@ -0,0 +1,68 @@
+package disruption
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func pod(namespace, name, app string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       namespace,
+		Labels:          map[string]string{"app": app},
+		OwnerReferences: checktest.Controller("StatefulSet", app),
+	}}
+}
+
+func pdb(namespace, name string, selector *metav1.LabelSelector) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+	}
+}
+
+func TestDisruption(t *testing.T) {
+	testcases := []struct {
+		desc    string
+		objects []runtime.Object
+		want    map[string]string
+	}{
+		{
+			desc: "Pods must be selected by a budget in their namespace",
+			objects: []runtime.Object{
+				pod("a", "web-0", "web"), pod("a", "db-0", "db"), pod("b", "web-0", "web"),
+				pdb("a", "web", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}),
+			},
+			want: map[string]string{"a/statefulset/web": "passed", "a/statefulset/db": "failed", "b/statefulset/web": "failed"},
+		}, {
+			desc: "Budgets without a selector select no pods",
+			objects: []runtime.Object{
+				pod("a", "web-0", "web"),
+				pdb("a", "all", nil),
+			},
+			want: map[string]string{"a/statefulset/web": "failed"},
+		}, {
+			desc: "System namespaces are not checked by default",
+			objects: []runtime.Object{
+				pod("kube-system", "dns-0", "dns"),
+			},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			item := checktest.Run(t, internal.CheckConfig{Kind: "v1alpha1/pod/disruption"}, checktest.Cluster{Objects: tc.objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
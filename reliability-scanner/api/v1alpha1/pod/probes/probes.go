@@ -6,30 +6,33 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var checkName string = "probes"
 
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/pod/probes",
+		Description: "Checks for whether liveness and readiness probes are defined.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourcePods},
+	})
+}
+
 // QuerierSpec defines the Specification for a Querier.
 type QuerierSpec struct {
+	PodDetail bool `yaml:"pod_detail" description:"Include the result of each Pod of a workload."`
 }
 
 // Querier defines the query and set of checks.
 type Querier struct {
-	client *kubernetes.Clientset
-	Spec   *QuerierSpec `yaml:"spec"`
-}
-
-// AddtoRunner configures a runner with the Querier for this check.
-func (querier *Querier) AddtoRunner(runner *internal.Runner) {
-	runner.Queriers = append(runner.Queriers, querier)
-	runner.Logger.WithFields(log.Fields{
-		"check_name": checkName,
-		"phase":      "add",
-	}).Info("complete")
+	Spec *QuerierSpec `yaml:"spec"`
 }
 
 // NewQuerier returns a new configured Querier.
@@ -37,14 +40,6 @@ func NewQuerier(spec *QuerierSpec) (Querier, error) {
 	out := Querier{
 		Spec: spec,
 	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return out, err
-	}
-	out.client, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return out, err
-	}
 	return out, nil
 }
 
@@ -60,12 +55,17 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		Status: "passed",
 	}
 
-	pods, err := q.client.CoreV1().Pods("").List(cfg.Context, metav1.ListOptions{})
+	pods, err := cfg.Cache.Pods.List(labels.Everything())
 	if err != nil {
 		checkItem.Status = "failed"
 	}
-	for _, pod := range pods.Items {
-		if pod.Name == "sonobuoy" || strings.HasPrefix(pod.Name, "sonobuoy-reliability-scanner-job") {
+	pods = cfg.Scope.Pods(pods)
+	internal.SortPods(pods)
+
+	var findings []internal.PodFinding
+	for _, pod := range pods {
+		if exemption, ok := cfg.Scope.ExemptPod(pod); ok {
+			checkItem.AddExemption(exemption)
 			continue
 		}
 
@@ -87,13 +87,13 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 				}
 			}
 		}
-		item := internal.Item{
-			Name:    pod.ObjectMeta.Name,
+		findings = append(findings, internal.PodFinding{
+			Pod:     pod,
 			Status:  status,
 			Details: details,
-		}
-		checkItem.Items = append(checkItem.Items, item)
+		})
 	}
+	checkItem.Items = cfg.Cache.WorkloadItems(findings, q.Spec.PodDetail)
 
 	cfg.Logger.WithFields(log.Fields{
 		"component":  "check",
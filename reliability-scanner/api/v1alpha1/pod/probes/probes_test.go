@@ -0,0 +1,93 @@
+package probes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func pod(name, owner string, containers ...corev1.Container) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app"},
+		Spec:       corev1.PodSpec{Containers: containers},
+	}
+	if owner != "" {
+		p.OwnerReferences = checktest.Controller("StatefulSet", owner)
+	}
+	return p
+}
+
+func container(readiness, liveness bool) corev1.Container {
+	c := corev1.Container{Name: "app"}
+	if readiness {
+		c.ReadinessProbe = &corev1.Probe{}
+	}
+	if liveness {
+		c.LivenessProbe = &corev1.Probe{}
+	}
+	return c
+}
+
+func TestProbes(t *testing.T) {
+	testcases := []struct {
+		desc           string
+		objects        []runtime.Object
+		want           map[string]string
+		wantExemptions []string
+	}{
+		{
+			desc: "Containers must define both probes",
+			objects: []runtime.Object{
+				pod("both", "", container(true, true)),
+				pod("readiness", "", container(true, false)),
+				pod("none", "", container(false, false)),
+			},
+			want: map[string]string{
+				"app/pod/both":      "passed",
+				"app/pod/readiness": "failed",
+				"app/pod/none":      "failed",
+			},
+		}, {
+			desc: "Replicas are reported once per workload",
+			objects: []runtime.Object{
+				pod("web-0", "web", container(true, true)),
+				pod("web-1", "web", container(true, false)),
+			},
+			want: map[string]string{"app/statefulset/web": "failed"},
+		}, {
+			desc: "Exempt workloads are listed rather than checked",
+			objects: []runtime.Object{
+				&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+					Name:        "batch",
+					Namespace:   "app",
+					Annotations: map[string]string{internal.ExemptAnnotation: "probes"},
+				}},
+				pod("batch-0", "batch", container(false, false)),
+			},
+			want:           map[string]string{},
+			wantExemptions: []string{"app/statefulset/batch"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			item := checktest.Run(t, internal.CheckConfig{Kind: "v1alpha1/pod/probes"}, checktest.Cluster{Objects: tc.objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+			var exemptions []string
+			for _, e := range item.Exemptions {
+				exemptions = append(exemptions, e.Name)
+			}
+			if !reflect.DeepEqual(exemptions, tc.wantExemptions) {
+				t.Errorf("Expected exemptions %v but got %v", tc.wantExemptions, exemptions)
+			}
+		})
+	}
+}
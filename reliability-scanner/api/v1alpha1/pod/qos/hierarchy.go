@@ -1,14 +1,12 @@
 package qos
 
-func meetsMinimum(test, minimum string) bool {
-	hierarchy := make(map[string]int)
-
-	hierarchy["Guaranteed"] = 3
-	hierarchy["Burstable"] = 2
-	hierarchy["BestEffort"] = 1
+// hierarchy orders the QOS classes from least to most desirable.
+var hierarchy = map[string]int{
+	"BestEffort": 1,
+	"Burstable":  2,
+	"Guaranteed": 3,
+}
 
-	if hierarchy[test] >= hierarchy[minimum] {
-		return true
-	}
-	return false
+func meetsMinimum(test, minimum string) bool {
+	return hierarchy[test] >= hierarchy[minimum]
 }
@@ -6,25 +6,48 @@ import (
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
 
 	log "github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var (
 	checkName string = "qos"
 )
 
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/pod/qos",
+		Description: "Checks each pod to see if the minimum desired QOS is defined.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{
+				MinimumDesiredQOSClass: "BestEffort",
+				IncludeDetail:          true,
+			}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourcePods},
+	})
+}
+
 // QuerierSpec defines the Specification for a Querier
 type QuerierSpec struct {
-	MinimumDesiredQOSClass string `yaml:"minimum_desired_qos_class"`
-	IncludeDetail          bool   `yaml:"include_detail"`
+	MinimumDesiredQOSClass string `yaml:"minimum_desired_qos_class" description:"The minimum desired QOS class for Pods: BestEffort, Burstable or Guaranteed."`
+	IncludeDetail          bool   `yaml:"include_detail" description:"Include the configured QOS class of each Pod."`
+	PodDetail              bool   `yaml:"pod_detail" description:"Include the result of each Pod of a workload."`
+}
+
+// Validate checks that the minimum desired QOS class is known.
+func (spec *QuerierSpec) Validate() error {
+	if _, ok := hierarchy[spec.MinimumDesiredQOSClass]; !ok {
+		return fmt.Errorf("unknown minimum_desired_qos_class %q", spec.MinimumDesiredQOSClass)
+	}
+	return nil
 }
 
 // Querier defines the query and set of checks
 type Querier struct {
-	client *kubernetes.Clientset
-	Spec   *QuerierSpec `yaml:"spec"`
+	Spec *QuerierSpec `yaml:"spec"`
 }
 
 // NewQuerier returns a new configured Querier
@@ -32,26 +55,9 @@ func NewQuerier(spec *QuerierSpec) (Querier, error) {
 	out := Querier{
 		Spec: spec,
 	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return out, err
-	}
-	out.client, err = kubernetes.NewForConfig(config)
-	if err != nil {
-		return out, err
-	}
 	return out, nil
 }
 
-// AddtoRunner configures a runner with the Querier for this check.
-func (querier *Querier) AddtoRunner(runner *internal.Runner) {
-	runner.Queriers = append(runner.Queriers, querier)
-	runner.Logger.WithFields(log.Fields{
-		"check_name": checkName,
-		"phase":      "add",
-	}).Info("complete")
-}
-
 // Start runs the Querier
 func (q Querier) Start(cfg *internal.QuerierConfig) {
 	cfg.Logger.WithFields(log.Fields{
@@ -64,28 +70,36 @@ func (q Querier) Start(cfg *internal.QuerierConfig) {
 		Status: "passed",
 	}
 
-	pods, err := q.client.CoreV1().Pods("").List(cfg.Context, metav1.ListOptions{})
+	pods, err := cfg.Cache.Pods.List(labels.Everything())
 	if err != nil {
 		checkItem.Status = "failed"
 	}
-
-	for _, pod := range pods.Items {
+	pods = cfg.Scope.Pods(pods)
+	internal.SortPods(pods)
+
+	var findings []internal.PodFinding
+	for _, pod := range pods {
+		if exemption, ok := cfg.Scope.ExemptPod(pod); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
 		details := make(map[string]interface{})
 
-		item := internal.Item{
-			Name:    fmt.Sprintf("%s/%s", pod.Namespace, pod.Name),
+		finding := internal.PodFinding{
+			Pod:     pod,
 			Status:  "failed",
 			Details: details,
 		}
 		if meetsMinimum(string(pod.Status.QOSClass), q.Spec.MinimumDesiredQOSClass) {
-			item.Status = "passed"
+			finding.Status = "passed"
 		}
 		if q.Spec.IncludeDetail {
 			details["qos_class"] = pod.Status.QOSClass
 		}
 
-		checkItem.Items = append(checkItem.Items, item)
+		findings = append(findings, finding)
 	}
+	checkItem.Items = cfg.Cache.WorkloadItems(findings, q.Spec.PodDetail)
 
 	cfg.Logger.WithFields(log.Fields{
 		"check_name": checkName,
@@ -0,0 +1,66 @@
+package qos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func pod(name string, class corev1.PodQOSClass) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app"},
+		Status:     corev1.PodStatus{QOSClass: class},
+	}
+}
+
+func TestQOS(t *testing.T) {
+	objects := []runtime.Object{
+		pod("besteffort", corev1.PodQOSBestEffort),
+		pod("burstable", corev1.PodQOSBurstable),
+		pod("guaranteed", corev1.PodQOSGuaranteed),
+	}
+	testcases := []struct {
+		desc      string
+		spec      map[string]interface{}
+		want      map[string]string
+		expectErr string
+	}{
+		{
+			desc: "Every class meets the default minimum",
+			want: map[string]string{"app/pod/besteffort": "passed", "app/pod/burstable": "passed", "app/pod/guaranteed": "passed"},
+		}, {
+			desc: "Classes below the minimum fail",
+			spec: map[string]interface{}{"minimum_desired_qos_class": "Burstable"},
+			want: map[string]string{"app/pod/besteffort": "failed", "app/pod/burstable": "passed", "app/pod/guaranteed": "passed"},
+		}, {
+			desc: "Only Guaranteed meets a Guaranteed minimum",
+			spec: map[string]interface{}{"minimum_desired_qos_class": "Guaranteed"},
+			want: map[string]string{"app/pod/besteffort": "failed", "app/pod/burstable": "failed", "app/pod/guaranteed": "passed"},
+		}, {
+			desc:      "Unknown classes are rejected",
+			spec:      map[string]interface{}{"minimum_desired_qos_class": "Platinum"},
+			expectErr: `invalid spec: unknown minimum_desired_qos_class "Platinum"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := internal.CheckConfig{Kind: "v1alpha1/pod/qos", Spec: tc.spec}
+			if len(tc.expectErr) > 0 {
+				if _, err := internal.NewQuerier(cfg); err == nil || err.Error() != tc.expectErr {
+					t.Fatalf("Expected error %q but got %v", tc.expectErr, err)
+				}
+				return
+			}
+			item := checktest.Run(t, cfg, checktest.Cluster{Objects: objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var checkName string = "resources"
+
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/pod/resources",
+		Description: "Checks containers and init containers for CPU and memory requests and limits, and namespaces for a LimitRange and ResourceQuota.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{
+				RequireCPURequest:    true,
+				RequireMemoryRequest: true,
+				RequireMemoryLimit:   true,
+			}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourcePods, internal.ResourceNamespaces, internal.ResourceLimitRanges, internal.ResourceResourceQuotas},
+	})
+}
+
+// QuerierSpec defines the Specification for a Querier.
+type QuerierSpec struct {
+	RequireCPURequest    bool    `yaml:"require_cpu_request" description:"Require each container and init container to set a CPU request."`
+	RequireMemoryRequest bool    `yaml:"require_memory_request" description:"Require each container and init container to set a memory request."`
+	RequireCPULimit      bool    `yaml:"require_cpu_limit" description:"Require each container and init container to set a CPU limit."`
+	RequireMemoryLimit   bool    `yaml:"require_memory_limit" description:"Require each container and init container to set a memory limit."`
+	MaxLimitRequestRatio float64 `yaml:"max_limit_request_ratio" description:"The maximum ratio of a limit to its request, unchecked if 0."`
+	RequireLimitRange    bool    `yaml:"require_limit_range" description:"Require each Namespace to have a LimitRange, even if it has a ResourceQuota."`
+	RequireResourceQuota bool    `yaml:"require_resource_quota" description:"Require each Namespace to have a ResourceQuota, even if it has a LimitRange."`
+	PodDetail            bool    `yaml:"pod_detail" description:"Include the result of each Pod of a workload."`
+}
+
+// Validate checks that the limit to request ratio is usable.
+func (spec *QuerierSpec) Validate() error {
+	if spec.MaxLimitRequestRatio != 0 && spec.MaxLimitRequestRatio < 1 {
+		return fmt.Errorf("max_limit_request_ratio must be at least 1, got %v", spec.MaxLimitRequestRatio)
+	}
+	return nil
+}
+
+// Querier defines the query and set of checks.
+type Querier struct {
+	Spec *QuerierSpec `yaml:"spec"`
+}
+
+// NewQuerier returns a new configured Querier.
+func NewQuerier(spec *QuerierSpec) (Querier, error) {
+	out := Querier{
+		Spec: spec,
+	}
+	return out, nil
+}
+
+// Start runs the Querier.
+func (q Querier) Start(cfg *internal.QuerierConfig) {
+	cfg.Logger.WithFields(log.Fields{
+		"check_name": checkName,
+		"phase":      "add",
+	}).Info(internal.CheckStartMsg)
+
+	checkItem := internal.ReportItem{
+		Name:   checkName,
+		Status: "passed",
+	}
+
+	pods, err := cfg.Cache.Pods.List(labels.Everything())
+	if err != nil {
+		checkItem.Status = "failed"
+	}
+	pods = cfg.Scope.Pods(pods)
+	internal.SortPods(pods)
+
+	var findings []internal.PodFinding
+	for _, pod := range pods {
+		if exemption, ok := cfg.Scope.ExemptPod(pod); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+
+		details := make(map[string]interface{})
+		status := "passed"
+		// Container names are unique across containers and init containers, so they can share details.
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			if problems := q.containerProblems(container); len(problems) > 0 {
+				status = "failed"
+				details[container.Name] = map[string]string{
+					"error": strings.Join(problems, "; "),
+				}
+			}
+		}
+		findings = append(findings, internal.PodFinding{
+			Pod:     pod,
+			Status:  status,
+			Details: details,
+		})
+	}
+	checkItem.Items = cfg.Cache.WorkloadItems(findings, q.Spec.PodDetail)
+
+	if q.Spec.RequireLimitRange || q.Spec.RequireResourceQuota {
+		items, err := q.namespaceItems(cfg, &checkItem)
+		if err != nil {
+			checkItem.Status = "failed"
+		}
+		checkItem.Items = append(checkItem.Items, items...)
+	}
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "complete",
+	}).Info(internal.CheckCompleteMsg)
+
+	cfg.Results <- checkItem
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "write",
+	}).Info(internal.CheckWriteMsg)
+}
+
+// containerProblems returns why the container's resources do not meet the spec.
+func (q Querier) containerProblems(container corev1.Container) []string {
+	var missing []string
+	required := []struct {
+		required bool
+		list     corev1.ResourceList
+		name     corev1.ResourceName
+		desc     string
+	}{
+		{q.Spec.RequireCPURequest, container.Resources.Requests, corev1.ResourceCPU, "cpu request"},
+		{q.Spec.RequireMemoryRequest, container.Resources.Requests, corev1.ResourceMemory, "memory request"},
+		{q.Spec.RequireCPULimit, container.Resources.Limits, corev1.ResourceCPU, "cpu limit"},
+		{q.Spec.RequireMemoryLimit, container.Resources.Limits, corev1.ResourceMemory, "memory limit"},
+	}
+	for _, r := range required {
+		if _, ok := r.list[r.name]; r.required && !ok {
+			missing = append(missing, r.desc)
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("Please define the following: %s", strings.Join(missing, ",")))
+	}
+	if q.Spec.MaxLimitRequestRatio > 0 {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			request, hasRequest := container.Resources.Requests[name]
+			limit, hasLimit := container.Resources.Limits[name]
+			if !hasRequest || !hasLimit || request.MilliValue() == 0 {
+				continue
+			}
+			ratio := float64(limit.MilliValue()) / float64(request.MilliValue())
+			if ratio > q.Spec.MaxLimitRequestRatio {
+				problems = append(problems, fmt.Sprintf("%s limit %s is %.1fx the request %s, above the maximum of %v",
+					name, limit.String(), ratio, request.String(), q.Spec.MaxLimitRequestRatio))
+			}
+		}
+	}
+	return problems
+}
+
+// namespaceItems returns an Item for each namespace in scope, which fails if it is missing any of
+// the required LimitRange and ResourceQuota. Having one does not satisfy a requirement for the other.
+func (q Querier) namespaceItems(cfg *internal.QuerierConfig, checkItem *internal.ReportItem) ([]internal.Item, error) {
+	namespaces, err := cfg.Cache.Namespaces.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	namespaces = cfg.Scope.Namespaces(namespaces)
+	internal.SortNamespaces(namespaces)
+
+	var items []internal.Item
+	for _, namespace := range namespaces {
+		if exemption, ok := cfg.Scope.Exempt(namespace.Name, "Namespace", namespace); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+
+		limitRanges, err := cfg.Cache.LimitRanges.LimitRanges(namespace.Name).List(labels.Everything())
+		if err != nil {
+			return items, err
+		}
+		quotas, err := cfg.Cache.ResourceQuotas.ResourceQuotas(namespace.Name).List(labels.Everything())
+		if err != nil {
+			return items, err
+		}
+
+		var missing []string
+		if q.Spec.RequireLimitRange && len(limitRanges) == 0 {
+			missing = append(missing, "LimitRange")
+		}
+		if q.Spec.RequireResourceQuota && len(quotas) == 0 {
+			missing = append(missing, "ResourceQuota")
+		}
+		item := internal.Item{
+			Name:      namespace.Name,
+			Namespace: namespace.Name,
+			Status:    "passed",
+			Details: map[string]interface{}{
+				"kind":            "Namespace",
+				"limit_ranges":    len(limitRanges),
+				"resource_quotas": len(quotas),
+			},
+		}
+		if len(missing) > 0 {
+			item.Status = "failed"
+			item.Details["error"] = fmt.Sprintf("Please define the following: %s", strings.Join(missing, ","))
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
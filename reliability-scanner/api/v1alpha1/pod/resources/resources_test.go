@@ -0,0 +1,120 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func pod(name string, requests, limits corev1.ResourceList) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:      "app",
+			Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+		}}},
+	}
+}
+
+func withInit(p *corev1.Pod, requests, limits corev1.ResourceList) *corev1.Pod {
+	p.Spec.InitContainers = []corev1.Container{{
+		Name:      "init",
+		Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+	}}
+	return p
+}
+
+func resources(cpu, memory string) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	if cpu != "" {
+		out[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		out[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return out
+}
+
+func namespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestResources(t *testing.T) {
+	testcases := []struct {
+		desc      string
+		spec      map[string]interface{}
+		objects   []runtime.Object
+		want      map[string]string
+		expectErr string
+	}{
+		{
+			desc: "Containers need a cpu and memory request and a memory limit by default",
+			objects: []runtime.Object{
+				pod("complete", resources("100m", "64Mi"), resources("", "64Mi")),
+				pod("no-limit", resources("100m", "64Mi"), nil),
+				pod("no-cpu", resources("", "64Mi"), resources("", "64Mi")),
+			},
+			want: map[string]string{"app/pod/complete": "passed", "app/pod/no-limit": "failed", "app/pod/no-cpu": "failed"},
+		}, {
+			desc: "Nothing is required when disabled",
+			spec: map[string]interface{}{"require_cpu_request": false, "require_memory_request": false, "require_memory_limit": false},
+			objects: []runtime.Object{
+				pod("none", nil, nil),
+			},
+			want: map[string]string{"app/pod/none": "passed"},
+		}, {
+			desc: "Limits may not exceed the ratio to their request",
+			spec: map[string]interface{}{"max_limit_request_ratio": 2},
+			objects: []runtime.Object{
+				pod("within", resources("100m", "64Mi"), resources("200m", "128Mi")),
+				pod("cpu", resources("100m", "64Mi"), resources("1", "64Mi")),
+				pod("memory", resources("100m", "64Mi"), resources("", "1Gi")),
+			},
+			want: map[string]string{"app/pod/within": "passed", "app/pod/cpu": "failed", "app/pod/memory": "failed"},
+		}, {
+			desc: "Init containers are checked as well",
+			objects: []runtime.Object{
+				withInit(pod("init-complete", resources("100m", "64Mi"), resources("", "64Mi")), resources("50m", "32Mi"), resources("", "32Mi")),
+				withInit(pod("init-no-limit", resources("100m", "64Mi"), resources("", "64Mi")), resources("50m", "32Mi"), nil),
+			},
+			want: map[string]string{"app/pod/init-complete": "passed", "app/pod/init-no-limit": "failed"},
+		}, {
+			desc: "Namespaces need a LimitRange and ResourceQuota when required",
+			spec: map[string]interface{}{"require_limit_range": true, "require_resource_quota": true},
+			objects: []runtime.Object{
+				namespace("app"), namespace("limited"), namespace("quota"), namespace("complete"), namespace("kube-system"),
+				&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "limited"}},
+				&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "quota"}},
+				&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "complete"}},
+				&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "quota", Namespace: "complete"}},
+			},
+			want: map[string]string{"app": "failed", "limited": "failed", "quota": "failed", "complete": "passed"},
+		}, {
+			desc:      "Ratios below 1 are rejected",
+			spec:      map[string]interface{}{"max_limit_request_ratio": 0.5},
+			expectErr: "invalid spec: max_limit_request_ratio must be at least 1, got 0.5",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := internal.CheckConfig{Kind: "v1alpha1/pod/resources", Spec: tc.spec}
+			if len(tc.expectErr) > 0 {
+				if _, err := internal.NewQuerier(cfg); err == nil || err.Error() != tc.expectErr {
+					t.Fatalf("Expected error %q but got %v", tc.expectErr, err)
+				}
+				return
+			}
+			item := checktest.Run(t, cfg, checktest.Cluster{Objects: tc.objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
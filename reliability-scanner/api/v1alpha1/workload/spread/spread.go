@@ -0,0 +1,336 @@
+package spread
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+var checkName string = "spread"
+
+const (
+	zoneLabel     = "topology.kubernetes.io/zone"
+	hostnameLabel = "kubernetes.io/hostname"
+)
+
+// spreadKinds are the kinds of workload whose replicas are interchangeable, so should be spread.
+// DaemonSets run once per node, and Jobs and bare Pods are not replicated.
+var spreadKinds = map[string]bool{
+	"Deployment":  true,
+	"StatefulSet": true,
+	"ReplicaSet":  true,
+}
+
+func init() {
+	internal.Register(internal.CheckDefinition{
+		Kind:        "v1alpha1/workload/spread",
+		Description: "Checks that the replicas of each workload are spread across nodes and zones.",
+		NewSpec: func() interface{} {
+			return &QuerierSpec{
+				MinReplicas:       2,
+				RequireNodeSpread: true,
+				RequireZoneSpread: true,
+			}
+		},
+		New: func(spec interface{}) (internal.Querier, error) {
+			return NewQuerier(spec.(*QuerierSpec))
+		},
+		Resources: []internal.Resource{internal.ResourcePods, internal.ResourceNodes},
+	})
+}
+
+// QuerierSpec defines the Specification for a Querier.
+type QuerierSpec struct {
+	MinReplicas        int  `yaml:"min_replicas" description:"Only check workloads with at least this many scheduled replicas."`
+	RequireNodeSpread  bool `yaml:"require_node_spread" description:"Fail workloads whose replicas share a node while other nodes are available."`
+	RequireZoneSpread  bool `yaml:"require_zone_spread" description:"Fail workloads whose replicas share a zone while other zones are available."`
+	RequireConstraints bool `yaml:"require_constraints" description:"Fail workloads which declare neither topologySpreadConstraints nor pod anti-affinity."`
+}
+
+// Validate checks that only replicated workloads are checked.
+func (spec *QuerierSpec) Validate() error {
+	if spec.MinReplicas < 2 {
+		return fmt.Errorf("min_replicas must be at least 2, got %d", spec.MinReplicas)
+	}
+	return nil
+}
+
+// Querier defines the query and set of checks.
+type Querier struct {
+	Spec *QuerierSpec `yaml:"spec"`
+}
+
+// NewQuerier returns a new configured Querier.
+func NewQuerier(spec *QuerierSpec) (Querier, error) {
+	out := Querier{
+		Spec: spec,
+	}
+	return out, nil
+}
+
+// Start runs the Querier.
+func (q Querier) Start(cfg *internal.QuerierConfig) {
+	cfg.Logger.WithFields(log.Fields{
+		"check_name": checkName,
+		"phase":      "add",
+	}).Info(internal.CheckStartMsg)
+
+	checkItem := internal.ReportItem{
+		Name:   checkName,
+		Status: "passed",
+	}
+
+	nodes, err := cfg.Cache.Nodes.List(labels.Everything())
+	if err != nil {
+		checkItem.Status = "failed"
+	}
+	topology := newTopology(nodes)
+
+	pods, err := cfg.Cache.Pods.List(labels.Everything())
+	if err != nil {
+		checkItem.Status = "failed"
+	}
+	pods = cfg.Scope.Pods(pods)
+	internal.SortPods(pods)
+
+	var order []internal.Workload
+	byWorkload := make(map[internal.Workload][]*corev1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		w := cfg.Cache.WorkloadFor(pod)
+		if !spreadKinds[w.Kind] {
+			continue
+		}
+		if _, ok := byWorkload[w]; !ok {
+			order = append(order, w)
+		}
+		byWorkload[w] = append(byWorkload[w], pod)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].String() < order[j].String() })
+
+	for _, w := range order {
+		replicas := byWorkload[w]
+		if len(replicas) < q.Spec.MinReplicas {
+			continue
+		}
+		if exemption, ok := cfg.Scope.ExemptPod(replicas[0]); ok {
+			checkItem.AddExemption(exemption)
+			continue
+		}
+		checkItem.Items = append(checkItem.Items, q.workloadItem(w, replicas, topology))
+	}
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "complete",
+	}).Info(internal.CheckCompleteMsg)
+
+	cfg.Results <- checkItem
+
+	cfg.Logger.WithFields(log.Fields{
+		"component":  "check",
+		"check_name": checkName,
+		"phase":      "write",
+	}).Info(internal.CheckWriteMsg)
+}
+
+// topology is the failure domains of the nodes in the cluster. Hosts are identified by their
+// hostname label, falling back to the node name.
+type topology struct {
+	hostOf map[string]string
+	zoneOf map[string]string
+	// schedulable are the nodes which are not cordoned.
+	schedulable []node
+}
+
+type node struct {
+	name   string
+	host   string
+	zone   string
+	labels labels.Set
+	taints []corev1.Taint
+}
+
+func newTopology(nodes []*corev1.Node) topology {
+	t := topology{
+		hostOf: make(map[string]string),
+		zoneOf: make(map[string]string),
+	}
+	for _, n := range nodes {
+		host := n.Labels[hostnameLabel]
+		if host == "" {
+			host = n.Name
+		}
+		t.hostOf[n.Name] = host
+		t.zoneOf[n.Name] = n.Labels[zoneLabel]
+		if n.Spec.Unschedulable {
+			continue
+		}
+		t.schedulable = append(t.schedulable, node{
+			name:   n.Name,
+			host:   host,
+			zone:   n.Labels[zoneLabel],
+			labels: labels.Set(n.Labels),
+			taints: n.Spec.Taints,
+		})
+	}
+	return t
+}
+
+// available returns the number of hosts and zones the pod can be scheduled to, i.e. those with a
+// schedulable node which matches the pod's node selector and required node affinity, and whose
+// NoSchedule and NoExecute taints the pod tolerates.
+func (t topology) available(pod *corev1.Pod) (int, int) {
+	hosts := make(map[string]bool)
+	zones := make(map[string]bool)
+	for _, n := range t.schedulable {
+		if !selects(pod, n) || !tolerates(pod, n.taints) {
+			continue
+		}
+		hosts[n.host] = true
+		if n.zone != "" {
+			zones[n.zone] = true
+		}
+	}
+	return len(hosts), len(zones)
+}
+
+// selects returns whether the node matches the pod's nodeSelector and, if set, any of the terms of
+// its requiredDuringSchedulingIgnoredDuringExecution node affinity.
+func selects(pod *corev1.Pod, n node) bool {
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(n.labels) {
+		return false
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesTerm(term, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTerm returns whether the node matches every requirement of the term, the way the scheduler
+// does. Terms without requirements match no nodes and the only supported field is metadata.name.
+func matchesTerm(term corev1.NodeSelectorTerm, n node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, expr := range term.MatchExpressions {
+		r, err := labels.NewRequirement(expr.Key, selectorOps[expr.Operator], expr.Values)
+		if err != nil || !r.Matches(n.labels) {
+			return false
+		}
+	}
+	for _, expr := range term.MatchFields {
+		if expr.Key != "metadata.name" {
+			return false
+		}
+		r, err := labels.NewRequirement(expr.Key, selectorOps[expr.Operator], expr.Values)
+		if err != nil || !r.Matches(fields.Set{expr.Key: n.name}) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorOps maps node selector operators to their label selector equivalents.
+var selectorOps = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// tolerates returns whether the pod tolerates every taint which prevents scheduling or running on a node.
+func tolerates(pod *corev1.Pod, taints []corev1.Taint) bool {
+	for i := range taints {
+		taint := &taints[i]
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// workloadItem compares the placement of the replicas with the nodes and zones available to them.
+func (q Querier) workloadItem(w internal.Workload, replicas []*corev1.Pod, t topology) internal.Item {
+	hosts := make(map[string]int)
+	zones := make(map[string]int)
+	for _, pod := range replicas {
+		host := t.hostOf[pod.Spec.NodeName]
+		if host == "" {
+			host = pod.Spec.NodeName
+		}
+		hosts[host]++
+		if zone := t.zoneOf[pod.Spec.NodeName]; zone != "" {
+			zones[zone]++
+		}
+	}
+	availableHosts, availableZones := t.available(replicas[0])
+	spec := replicas[0].Spec
+	spreadConstraints := len(spec.TopologySpreadConstraints) > 0
+	antiAffinity := spec.Affinity != nil && spec.Affinity.PodAntiAffinity != nil
+
+	var problems []string
+	if want := min(len(replicas), availableHosts); q.Spec.RequireNodeSpread && len(hosts) < want {
+		problems = append(problems, fmt.Sprintf("%d replicas are scheduled on %d of %d available nodes", len(replicas), len(hosts), availableHosts))
+	}
+	if want := min(len(replicas), availableZones); q.Spec.RequireZoneSpread && len(zones) < want {
+		problems = append(problems, fmt.Sprintf("%d replicas are scheduled in %d of %d available zones", len(replicas), len(zones), availableZones))
+	}
+	if q.Spec.RequireConstraints && !spreadConstraints && !antiAffinity {
+		problems = append(problems, "Please define the following: topologySpreadConstraints or podAntiAffinity")
+	}
+
+	item := internal.Item{
+		Name:      w.String(),
+		Namespace: w.Namespace,
+		Status:    "passed",
+		Details: map[string]interface{}{
+			"kind":                        w.Kind,
+			"replicas":                    len(replicas),
+			"nodes":                       len(hosts),
+			"zones":                       zones,
+			"topology_spread_constraints": spreadConstraints,
+			"pod_anti_affinity":           antiAffinity,
+		},
+	}
+	if len(problems) > 0 {
+		item.Status = "failed"
+		item.Details["error"] = strings.Join(problems, "; ")
+	}
+	return item
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
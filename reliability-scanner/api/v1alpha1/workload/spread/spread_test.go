@@ -0,0 +1,169 @@
+package spread
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal/checktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func testNode(name, zone string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{zoneLabel: zone}},
+		Spec:       corev1.NodeSpec{Taints: taints},
+	}
+}
+
+func replica(name, owner, nodeName string, tolerations ...corev1.Toleration) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", OwnerReferences: checktest.Controller("StatefulSet", owner)},
+		Spec:       corev1.PodSpec{NodeName: nodeName, Tolerations: tolerations},
+	}
+}
+
+func withLabels(n *corev1.Node, set map[string]string) *corev1.Node {
+	for k, v := range set {
+		n.Labels[k] = v
+	}
+	return n
+}
+
+func withNodeAffinity(p *corev1.Pod, terms ...corev1.NodeSelectorTerm) *corev1.Pod {
+	p.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+	}}
+	return p
+}
+
+var gpuTaint = corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+func TestSpread(t *testing.T) {
+	testcases := []struct {
+		desc    string
+		spec    map[string]interface{}
+		objects []runtime.Object
+		want    map[string]string
+	}{
+		{
+			desc: "Replicas sharing a node fail while other nodes are available",
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2"),
+				replica("db-0", "db", "n1"), replica("db-1", "db", "n2"),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+			},
+			want: map[string]string{"app/statefulset/db": "passed", "app/statefulset/web": "failed"},
+		}, {
+			desc: "Cordoned nodes are not available",
+			objects: []runtime.Object{
+				testNode("n1", "z1"),
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n2", Labels: map[string]string{zoneLabel: "z2"}}, Spec: corev1.NodeSpec{Unschedulable: true}},
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+			},
+			want: map[string]string{"app/statefulset/web": "passed"},
+		}, {
+			desc: "Nodes with taints the replicas do not tolerate are not available",
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2", gpuTaint),
+				testNode("n3", "z2", corev1.Taint{Key: "drain", Effect: corev1.TaintEffectNoExecute}),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+			},
+			want: map[string]string{"app/statefulset/web": "passed"},
+		}, {
+			desc: "Nodes which prefer not to be scheduled to are available",
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2", corev1.Taint{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule}),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+			},
+			want: map[string]string{"app/statefulset/web": "failed"},
+		}, {
+			desc: "Tainted nodes are available to replicas which tolerate them",
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2", gpuTaint),
+				replica("train-0", "train", "n1", corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpExists}),
+				replica("train-1", "train", "n1", corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpExists}),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+			},
+			want: map[string]string{"app/statefulset/train": "failed", "app/statefulset/web": "passed"},
+		}, {
+			desc: "Nodes which do not match the replicas' node selector are not available",
+			objects: []runtime.Object{
+				withLabels(testNode("n1", "z1"), map[string]string{"pool": "db"}),
+				withLabels(testNode("n2", "z2"), map[string]string{"pool": "web"}),
+				withLabels(testNode("n3", "z2"), map[string]string{"pool": "db"}),
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "app", OwnerReferences: checktest.Controller("StatefulSet", "web")},
+					Spec:       corev1.PodSpec{NodeName: "n2", NodeSelector: map[string]string{"pool": "web"}},
+				},
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "app", OwnerReferences: checktest.Controller("StatefulSet", "web")},
+					Spec:       corev1.PodSpec{NodeName: "n2", NodeSelector: map[string]string{"pool": "web"}},
+				},
+			},
+			want: map[string]string{"app/statefulset/web": "passed"},
+		}, {
+			desc: "Nodes which match none of the replicas' required node affinity terms are not available",
+			objects: []runtime.Object{
+				withLabels(testNode("n1", "z1"), map[string]string{"pool": "db"}),
+				withLabels(testNode("n2", "z2"), map[string]string{"pool": "web"}),
+				withLabels(testNode("n3", "z2"), map[string]string{"pool": "batch"}),
+				testNode("n4", "z3"),
+				withNodeAffinity(replica("web-0", "web", "n2"),
+					corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"web"}}}},
+					corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"n3"}}}},
+				),
+				withNodeAffinity(replica("web-1", "web", "n2"),
+					corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"web"}}}},
+					corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"n3"}}}},
+				),
+				withNodeAffinity(replica("db-0", "db", "n1"),
+					corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"web"}}}},
+				),
+				withNodeAffinity(replica("db-1", "db", "n1"),
+					corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"web"}}}},
+				),
+			},
+			want: map[string]string{"app/statefulset/db": "failed", "app/statefulset/web": "failed"},
+		}, {
+			desc: "Replicas sharing a zone fail while other zones are available",
+			spec: map[string]interface{}{"require_node_spread": false},
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z1"), testNode("n3", "z2"),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n2"),
+			},
+			want: map[string]string{"app/statefulset/web": "failed"},
+		}, {
+			desc: "Workloads below min_replicas and other kinds are not checked",
+			spec: map[string]interface{}{"min_replicas": 3},
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2"),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n1"),
+				&corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "agent-a", Namespace: "app", OwnerReferences: checktest.Controller("DaemonSet", "agent")},
+					Spec:       corev1.PodSpec{NodeName: "n1"},
+				},
+			},
+			want: map[string]string{},
+		}, {
+			desc: "Constraints are required when configured",
+			spec: map[string]interface{}{"require_constraints": true},
+			objects: []runtime.Object{
+				testNode("n1", "z1"), testNode("n2", "z2"),
+				replica("web-0", "web", "n1"), replica("web-1", "web", "n2"),
+			},
+			want: map[string]string{"app/statefulset/web": "failed"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			item := checktest.Run(t, internal.CheckConfig{Kind: "v1alpha1/workload/spread", Spec: tc.spec}, checktest.Cluster{Objects: tc.objects})
+			if got := checktest.Statuses(item); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,114 +1,61 @@
 package main
 
 import (
-	"strconv"
-	"time"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
-	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/backup/staleness"
-	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/namespace/labels"
-	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/disruption"
-	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/probes"
-	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/qos"
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+
+	// Checks register their kinds with the internal registry when imported.
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/backup/staleness"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/cluster/certificates"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/namespace/labels"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/disruption"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/probes"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/qos"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/pod/resources"
+	_ "github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/api/v1alpha1/workload/spread"
 )
 
-// initializeQueriers sets up queriers based on the runners configuration.
+// initializeQueriers sets up queriers based on the runners configuration. Every check is validated
+// before any are added so that all configuration problems are reported at once.
 func initializeQueriers(runner *internal.Runner) error {
+	var queriers []internal.ConfiguredQuerier
+	var failed int
 	for _, checkCfg := range runner.Config.Checks {
-		switch checkCfg.Kind {
-		case "v1alpha1/pod/disruption":
-			querier, err := disruption.NewQuerier(&disruption.QuerierSpec{})
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"kind":  checkCfg.Spec["kind"],
-					"name":  checkCfg.Spec["name"],
-					"phase": "add",
-				}).Error(err)
-				return err
-			}
-			querier.AddtoRunner(runner)
-		case "v1alpha1/pod/probes":
-			querier, err := probes.NewQuerier(&probes.QuerierSpec{})
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"kind":  checkCfg.Spec["kind"],
-					"name":  checkCfg.Spec["name"],
-					"phase": "add",
-				}).Error(err)
-				return err
-			}
-			querier.AddtoRunner(runner)
-		case "v1alpha1/pod/qos":
-			includeDetail, err := strconv.ParseBool(checkCfg.Spec["include_detail"])
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"kind":  checkCfg.Spec["kind"],
-					"name":  checkCfg.Spec["name"],
-					"phase": "add",
-				}).Error(err)
-				return err
-			}
-			querier, err := qos.NewQuerier(&qos.QuerierSpec{
-				IncludeDetail:          includeDetail,
-				MinimumDesiredQOSClass: checkCfg.Spec["minimum_desired_qos_class"],
-			})
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"kind":  checkCfg.Spec["kind"],
-					"name":  checkCfg.Spec["name"],
-					"phase": "add",
-				}).Error(err)
-				return err
-			}
-			querier.AddtoRunner(runner)
-		case "v1alpha1/namespace/labels":
-			includeDetail, err := strconv.ParseBool(checkCfg.Spec["include_detail"])
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"check_name": checkCfg.Spec["Name"],
-					"phase":      "add",
-				}).Error(err)
-				return err
-			}
-			querier, err := labels.NewQuerier(&labels.QuerierSpec{
-				Key:           checkCfg.Spec["key"],
-				IncludeDetail: includeDetail,
-			})
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"check_name": checkCfg.Spec["Name"],
-					"phase":      "add",
-				}).Error(err)
-				return err
-			}
-			querier.AddtoRunner(runner)
-		case "v1alpha1/backup/staleness":
-			spec := staleness.QuerierSpec{
-				BackupNamespace: checkCfg.Spec["backup_namespace"],
-			}
-			if checkCfg.Spec["max_age"] != "" {
-				maxAge, err := time.ParseDuration(checkCfg.Spec["max_age"])
-				if err != nil {
-					runner.Logger.WithFields(logrus.Fields{
-						"check_name": checkCfg.Spec["Name"],
-						"phase":      "add",
-					}).Error(err)
-					return err
-				}
-				spec.MaxAge = maxAge
-			}
-			querier, err := staleness.NewQuerier(&spec)
-			if err != nil {
-				runner.Logger.WithFields(logrus.Fields{
-					"check_name": checkCfg.Spec["Name"],
-					"phase":      "add",
-				}).Error(err)
-				return err
-			}
-			querier.AddtoRunner(runner)
+		querier, err := internal.NewQuerier(checkCfg)
+		if err != nil {
+			runner.Logger.WithFields(logrus.Fields{
+				"kind":       checkCfg.Kind,
+				"check_name": checkCfg.Name,
+				"phase":      "add",
+			}).Error(err)
+			failed++
+			continue
 		}
-
+		scope, err := internal.NewScope(runner.Config.Scope, checkCfg, runner.Cache)
+		if err != nil {
+			runner.Logger.WithFields(logrus.Fields{
+				"kind":       checkCfg.Kind,
+				"check_name": checkCfg.Name,
+				"phase":      "add",
+			}).Error(err)
+			failed++
+			continue
+		}
+		def, _ := internal.LookupCheck(checkCfg.Kind)
+		queriers = append(queriers, internal.ConfiguredQuerier{
+			Config:    checkCfg,
+			Scope:     scope,
+			Querier:   querier,
+			Resources: def.Resources,
+		})
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks are misconfigured", failed, len(runner.Config.Checks))
+	}
+	for _, querier := range queriers {
+		runner.AddQuerier(querier)
 	}
 	return nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+)
+
+// pluginValues returns the scanner configuration from the ytt values shipped with the plugin.
+func pluginValues(t *testing.T) string {
+	b, err := ioutil.ReadFile("../../plugin/reliability-scanner-custom-values.lib.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	in := false
+	for _, line := range strings.Split(string(b), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#@ def config():"):
+			in = true
+		case strings.HasPrefix(line, "#@ end"):
+			in = false
+		case in:
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		t.Fatal("no configuration found in the plugin values")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestInitializeQueriers(t *testing.T) {
+	testcases := []struct {
+		desc      string
+		config    string
+		expectErr string
+	}{
+		{
+			desc:   "Shipped plugin values",
+			config: "",
+		}, {
+			desc: "Deprecated disruption spec field",
+			config: `
+checks:
+- name: disruption
+  kind: v1alpha1/pod/disruption
+  spec:
+    include_detail: true
+`,
+		}, {
+			desc: "Unknown spec field",
+			config: `
+checks:
+- name: disruption
+  kind: v1alpha1/pod/disruption
+  spec:
+    pod_details: true
+`,
+			expectErr: "1 of 1 checks are misconfigured",
+		}, {
+			desc: "Unknown kind",
+			config: `
+checks:
+- name: qos
+  kind: v1alpha1/pod/qos
+- name: missing
+  kind: v1alpha1/pod/missing
+`,
+			expectErr: "1 of 2 checks are misconfigured",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := tc.config
+			if config == "" {
+				config = pluginValues(t)
+			}
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := readConfig(path)
+			if err != nil {
+				t.Fatalf("Unexpected error reading config: %v", err)
+			}
+
+			logger := logrus.New()
+			logger.SetOutput(ioutil.Discard)
+			runner := &internal.Runner{Config: c, Logger: logger}
+			err = initializeQueriers(runner)
+			switch {
+			case err != nil && len(tc.expectErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.expectErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && err.Error() != tc.expectErr:
+				t.Fatalf("Expected error %q but got %q", tc.expectErr, err.Error())
+			case err == nil && len(runner.Queriers) != len(c.Checks):
+				t.Errorf("Expected %v queriers but got %v", len(c.Checks), len(runner.Queriers))
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+)
+
+// listChecks writes every registered check kind along with the fields of its spec.
+func listChecks(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i, def := range internal.RegisteredChecks() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s\n  %s\n", def.Kind, def.Description)
+		fields := def.Fields()
+		if len(fields) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "\n  FIELD\tTYPE\tDEFAULT\tDESCRIPTION")
+		for _, f := range fields {
+			def := f.Default
+			if def == "" {
+				def = "-"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", f.Name, f.Type, def, f.Description)
+		}
+	}
+	return w.Flush()
+}
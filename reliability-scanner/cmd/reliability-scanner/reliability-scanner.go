@@ -19,11 +19,39 @@ func main() {
 				Name:    "scan",
 				Aliases: []string{"s"},
 				Usage:   "run the scanner",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "kubeconfig",
+						Usage: "scan the cluster in the kubeconfig from outside the cluster, writing the report locally",
+					},
+					&cli.StringFlag{
+						Name:    "config",
+						Aliases: []string{"c"},
+						Usage:   "path to the scanner configuration, read from the CONFIG environment variable if unset",
+					},
+					&cli.StringFlag{
+						Name:  "results-dir",
+						Usage: "directory to write reliability.yaml to when scanning with a kubeconfig",
+						Value: ".",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					scan()
+					os.Exit(scan(scanOptions{
+						kubeconfig: c.String("kubeconfig"),
+						configFile: c.String("config"),
+						resultsDir: c.String("results-dir"),
+					}))
 					return nil
 				},
 			},
+			{
+				Name:    "list-checks",
+				Aliases: []string{"ls"},
+				Usage:   "list the kinds of checks which may be configured",
+				Action: func(c *cli.Context) error {
+					return listChecks(os.Stdout)
+				},
+			},
 		},
 	}
 	err := app.Run(os.Args)
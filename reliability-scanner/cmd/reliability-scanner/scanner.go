@@ -9,58 +9,131 @@ import (
 	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
 )
 
-func scan() {
+// Exit codes of a scan run with a kubeconfig once the report has been written. The scanner exits
+// with 1 if it could not run or write the report.
+const (
+	exitPassed  = 0
+	exitFailed  = 2
+	exitErrored = 3
+)
+
+// scanOptions configures a scan. When a kubeconfig is given the scan runs outside the cluster,
+// reading its configuration from a file and writing the report to a local directory.
+type scanOptions struct {
+	kubeconfig string
+	configFile string
+	resultsDir string
+}
+
+// local returns whether the scan is running outside of a Sonobuoy plugin.
+func (o scanOptions) local() bool {
+	return o.kubeconfig != ""
+}
+
+// scan runs the configured checks and writes the report, returning the exit code for the process.
+func scan(opts scanOptions) int {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetOutput(os.Stdout)
 	logger.SetLevel(logrus.InfoLevel)
 	logger.WithField("component", "main")
 	logger.WithField("phase", "config")
-	cfg := os.Getenv("CONFIG")
-	f, err := os.Create("./config.yaml")
-	if err != nil {
-		logger.Fatal(err)
-	}
-	defer f.Close()
-	_, err = f.WriteString(cfg)
+
+	c, err := readConfig(opts.configFile)
 	if err != nil {
 		logger.Fatal(err)
 	}
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	err = viper.ReadInConfig()
+
+	restConfig, err := internal.NewRestConfig(opts.kubeconfig)
 	if err != nil {
 		logger.Fatal(err)
 	}
-
-	var c internal.ReliabilityConfig
-
-	err = viper.Unmarshal(&c)
+	client, dynamicClient, err := internal.NewClients(restConfig)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
+	ctx := context.Background()
 	runner := &internal.Runner{
-		Config:   &c,
-		Context:  context.Background(),
-		Results:  make(chan internal.ReportItem),
-		Logger:   logger,
-		Complete: make(chan struct{}),
+		Config:        c,
+		Context:       ctx,
+		RestConfig:    restConfig,
+		Client:        client,
+		DynamicClient: dynamicClient,
+		Cache:         internal.NewCache(client),
+		Results:       make(chan internal.ReportItem),
+		Logger:        logger,
+		Complete:      make(chan struct{}),
 	}
 	err = initializeQueriers(runner)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
+	// Checks which read a resource that did not sync report an error rather than failing the scan.
+	err = runner.Cache.Sync(ctx, internal.DefaultCacheSyncTimeout)
+	if err != nil {
+		logger.Error(err)
+	}
+
 	runner.Run()
 	report := runner.BuildReport(len(c.Checks), reportName)
+	if opts.local() {
+		resultsPath, err := runner.WriteResults(report, opts.resultsDir)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		logger.WithField("path", resultsPath).Info("Reliability Scan Complete.")
+		return exitCode(report)
+	}
 	err = runner.WriteReport(report, os.Getenv("SONOBUOY_RESULTS_DIR"))
 	if err != nil {
-		logger.Error(err)
-		os.Exit(1)
+		logger.Fatal(err)
 	}
 	logger.Info("Reliability Scan Complete.")
-	for {
+	// Sonobuoy treats a non-zero exit as a plugin error, so the outcome is only reported in the results.
+	return exitPassed
+}
+
+func exitCode(report *internal.Report) int {
+	switch {
+	case report.Errored():
+		return exitErrored
+	case report.Status != internal.StatusPassed:
+		return exitFailed
+	default:
+		return exitPassed
+	}
+}
+
+// readConfig reads the scanner configuration from the file, or from the CONFIG environment
+// variable set from the plugin's ConfigMap when no file is given.
+func readConfig(configFile string) (*internal.ReliabilityConfig, error) {
+	if configFile == "" {
+		f, err := os.Create("./config.yaml")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		_, err = f.WriteString(os.Getenv("CONFIG"))
+		if err != nil {
+			return nil, err
+		}
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+	} else {
+		viper.SetConfigFile(configFile)
+	}
+	err := viper.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var c internal.ReliabilityConfig
+	err = viper.Unmarshal(&c)
+	if err != nil {
+		return nil, err
 	}
+	return &c, nil
 }
@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	policyv1beta1listers "k8s.io/client-go/listers/policy/v1beta1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultCacheSyncTimeout is how long to wait for the Cache to list the cluster.
+var DefaultCacheSyncTimeout = 5 * time.Minute
+
+// Resource is a kind of object which checks read from the Cache.
+type Resource string
+
+// Resources which checks may declare in their CheckDefinition.
+const (
+	ResourceNamespaces           Resource = "namespaces"
+	ResourcePods                 Resource = "pods"
+	ResourceNodes                Resource = "nodes"
+	ResourceLimitRanges          Resource = "limitranges"
+	ResourceResourceQuotas       Resource = "resourcequotas"
+	ResourcePodDisruptionBudgets Resource = "poddisruptionbudgets"
+	// ResourceWorkloads are the controllers pods are resolved to, e.g. Deployments and Jobs. They
+	// are read along with ResourcePods.
+	ResourceWorkloads Resource = "workloads"
+)
+
+// PodDisruptionBudgetLister lists PodDisruptionBudgets as policy/v1, whichever version the cluster serves.
+type PodDisruptionBudgetLister interface {
+	List(selector labels.Selector) ([]*policyv1.PodDisruptionBudget, error)
+}
+
+// Cache is an informer cache of the resources read by Queriers, so that the cluster is listed once
+// per scan rather than once per check. Only the listers of registered resources are set, and they
+// must only be read once the Cache has synced.
+type Cache struct {
+	client  kubernetes.Interface
+	factory informers.SharedInformerFactory
+
+	mu        sync.Mutex
+	informers map[Resource][]cache.SharedIndexInformer
+	unsynced  map[Resource]bool
+
+	Pods                 corelisters.PodLister
+	Namespaces           corelisters.NamespaceLister
+	Nodes                corelisters.NodeLister
+	LimitRanges          corelisters.LimitRangeLister
+	ResourceQuotas       corelisters.ResourceQuotaLister
+	PodDisruptionBudgets PodDisruptionBudgetLister
+	Deployments          appslisters.DeploymentLister
+	StatefulSets         appslisters.StatefulSetLister
+	DaemonSets           appslisters.DaemonSetLister
+	ReplicaSets          appslisters.ReplicaSetLister
+	Jobs                 batchlisters.JobLister
+}
+
+// NewCache returns a Cache for the cluster. Namespaces are always registered since every check's
+// Scope reads them. It is not populated until it is synced.
+func NewCache(client kubernetes.Interface) *Cache {
+	c := &Cache{
+		client:    client,
+		factory:   informers.NewSharedInformerFactory(client, 0),
+		informers: make(map[Resource][]cache.SharedIndexInformer),
+		unsynced:  make(map[Resource]bool),
+	}
+	c.Register(ResourceNamespaces)
+	return c
+}
+
+// Register adds informers for the resources so that their listers are populated when the Cache
+// is synced. Registering a resource more than once has no effect.
+func (c *Cache) Register(resources ...Resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range resources {
+		c.register(r)
+	}
+}
+
+func (c *Cache) register(r Resource) {
+	if _, ok := c.informers[r]; ok {
+		return
+	}
+	switch r {
+	case ResourceNamespaces:
+		i := c.factory.Core().V1().Namespaces()
+		c.Namespaces = i.Lister()
+		c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+	case ResourcePods:
+		i := c.factory.Core().V1().Pods()
+		c.Pods = i.Lister()
+		c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+		c.register(ResourceWorkloads)
+	case ResourceNodes:
+		i := c.factory.Core().V1().Nodes()
+		c.Nodes = i.Lister()
+		c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+	case ResourceLimitRanges:
+		i := c.factory.Core().V1().LimitRanges()
+		c.LimitRanges = i.Lister()
+		c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+	case ResourceResourceQuotas:
+		i := c.factory.Core().V1().ResourceQuotas()
+		c.ResourceQuotas = i.Lister()
+		c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+	case ResourcePodDisruptionBudgets:
+		if c.servesPolicyV1() {
+			i := c.factory.Policy().V1().PodDisruptionBudgets()
+			c.PodDisruptionBudgets = i.Lister()
+			c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+		} else {
+			i := c.factory.Policy().V1beta1().PodDisruptionBudgets()
+			c.PodDisruptionBudgets = v1beta1PodDisruptionBudgetLister{i.Lister()}
+			c.informers[r] = []cache.SharedIndexInformer{i.Informer()}
+		}
+	case ResourceWorkloads:
+		apps, batch := c.factory.Apps().V1(), c.factory.Batch().V1()
+		c.Deployments = apps.Deployments().Lister()
+		c.StatefulSets = apps.StatefulSets().Lister()
+		c.DaemonSets = apps.DaemonSets().Lister()
+		c.ReplicaSets = apps.ReplicaSets().Lister()
+		c.Jobs = batch.Jobs().Lister()
+		c.informers[r] = []cache.SharedIndexInformer{
+			apps.Deployments().Informer(),
+			apps.StatefulSets().Informer(),
+			apps.DaemonSets().Informer(),
+			apps.ReplicaSets().Informer(),
+			batch.Jobs().Informer(),
+		}
+	default:
+		panic(fmt.Sprintf("unknown cache resource %q", r))
+	}
+}
+
+// servesPolicyV1 returns whether the cluster serves PodDisruptionBudgets from policy/v1, which
+// replaced policy/v1beta1 in Kubernetes 1.21. If discovery fails for another reason, policy/v1
+// is assumed.
+func (c *Cache) servesPolicyV1() bool {
+	resources, err := c.client.Discovery().ServerResourcesForGroupVersion(policyv1.SchemeGroupVersion.String())
+	if err != nil {
+		return !apierrors.IsNotFound(err)
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == string(ResourcePodDisruptionBudgets) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync starts the informers and waits until every resource has been listed. The informers keep
+// watching the cluster until the context is done. Resources which did not sync within the timeout
+// are returned in the error and reported by Err, so that only the checks reading them fail.
+func (c *Cache) Sync(ctx context.Context, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.factory.Start(ctx.Done())
+
+	syncCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var unsynced []string
+	for r, informers := range c.informers {
+		for _, informer := range informers {
+			if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+				c.unsynced[r] = true
+			}
+		}
+		if c.unsynced[r] {
+			unsynced = append(unsynced, string(r))
+		}
+	}
+	if len(unsynced) > 0 {
+		sort.Strings(unsynced)
+		return fmt.Errorf("timed out waiting for cache to sync: %s", strings.Join(unsynced, ", "))
+	}
+	return nil
+}
+
+// Err returns an error if any of the resources did not sync, e.g. because they could not be listed.
+// Namespaces are always read by a check's Scope and workloads along with pods, so they are included.
+func (c *Cache) Err(resources ...Resource) error {
+	if c == nil {
+		return nil
+	}
+	needed := map[Resource]bool{ResourceNamespaces: true}
+	for _, r := range resources {
+		needed[r] = true
+		if r == ResourcePods {
+			needed[ResourceWorkloads] = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var unsynced []string
+	for r := range needed {
+		if c.unsynced[r] {
+			unsynced = append(unsynced, string(r))
+		}
+	}
+	if len(unsynced) > 0 {
+		sort.Strings(unsynced)
+		return fmt.Errorf("cache did not sync: %s", strings.Join(unsynced, ", "))
+	}
+	return nil
+}
+
+// v1beta1PodDisruptionBudgetLister converts policy/v1beta1 PodDisruptionBudgets to policy/v1 for
+// clusters which do not serve policy/v1.
+type v1beta1PodDisruptionBudgetLister struct {
+	lister policyv1beta1listers.PodDisruptionBudgetLister
+}
+
+func (l v1beta1PodDisruptionBudgetLister) List(selector labels.Selector) ([]*policyv1.PodDisruptionBudget, error) {
+	pdbs, err := l.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*policyv1.PodDisruptionBudget, 0, len(pdbs))
+	for _, pdb := range pdbs {
+		selector := pdb.Spec.Selector
+		// An empty selector selects no pods in policy/v1beta1 but every pod in policy/v1.
+		if selector != nil && len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+			selector = nil
+		}
+		out = append(out, &policyv1.PodDisruptionBudget{
+			ObjectMeta: pdb.ObjectMeta,
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable:   pdb.Spec.MinAvailable,
+				Selector:       selector,
+				MaxUnavailable: pdb.Spec.MaxUnavailable,
+			},
+		})
+	}
+	return out, nil
+}
+
+// SortPods sorts pods by namespace and name so that reports are stable between scans.
+func SortPods(pods []*corev1.Pod) {
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].Namespace != pods[j].Namespace {
+			return pods[i].Namespace < pods[j].Namespace
+		}
+		return pods[i].Name < pods[j].Name
+	})
+}
+
+// SortNamespaces sorts namespaces by name so that reports are stable between scans.
+func SortNamespaces(namespaces []*corev1.Namespace) {
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+}
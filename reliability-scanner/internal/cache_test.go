@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// syncedCache returns a Cache of the resources in a fake cluster of the objects, which is synced
+// until the test completes.
+func syncedCache(t *testing.T, client *fake.Clientset, resources ...Resource) *Cache {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := NewCache(client)
+	c.Register(resources...)
+	if err := c.Sync(ctx, time.Minute); err != nil {
+		t.Fatalf("Unexpected error syncing cache: %v", err)
+	}
+	return c
+}
+
+func TestCacheErr(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewCache(client)
+	c.Register(ResourcePods, ResourceNodes)
+
+	err := c.Sync(ctx, 500*time.Millisecond)
+	if err == nil || err.Error() != "timed out waiting for cache to sync: nodes" {
+		t.Fatalf("Expected nodes to fail to sync but got %v", err)
+	}
+
+	testcases := []struct {
+		desc      string
+		resources []Resource
+		expectErr string
+	}{
+		{
+			desc: "Checks reading only synced resources succeed",
+		}, {
+			desc:      "Checks reading pods succeed",
+			resources: []Resource{ResourcePods},
+		}, {
+			desc:      "Checks reading unsynced resources fail",
+			resources: []Resource{ResourcePods, ResourceNodes},
+			expectErr: "cache did not sync: nodes",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := c.Err(tc.resources...)
+			switch {
+			case err == nil && len(tc.expectErr) > 0:
+				t.Errorf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && err.Error() != tc.expectErr:
+				t.Errorf("Expected error %q but got %q", tc.expectErr, err)
+			}
+		})
+	}
+
+	var nilCache *Cache
+	if err := nilCache.Err(ResourceNodes); err != nil {
+		t.Errorf("Expected no error from a nil Cache but got %v", err)
+	}
+}
+
+func TestCacheRegister(t *testing.T) {
+	c := syncedCache(t, fake.NewSimpleClientset(), ResourceNodes)
+	if c.Namespaces == nil || c.Nodes == nil {
+		t.Errorf("Expected namespaces and nodes to be registered")
+	}
+	if c.Pods != nil || c.LimitRanges != nil || c.PodDisruptionBudgets != nil {
+		t.Errorf("Expected only registered resources to be cached")
+	}
+
+	c = syncedCache(t, fake.NewSimpleClientset(), ResourcePods)
+	if c.Pods == nil || c.ReplicaSets == nil || c.Jobs == nil {
+		t.Errorf("Expected pods to register workloads")
+	}
+}
+
+func TestCachePodDisruptionBudgetsV1beta1(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		},
+		&policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "app"},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+		},
+	)
+	// The cluster serves policy/v1 without PodDisruptionBudgets, as before Kubernetes 1.21.
+	client.Resources = []*metav1.APIResourceList{{GroupVersion: "policy/v1"}}
+	c := syncedCache(t, client, ResourcePodDisruptionBudgets)
+
+	pdbs, err := c.PodDisruptionBudgets.List(labels.Everything())
+	if err != nil {
+		t.Fatalf("Unexpected error listing budgets: %v", err)
+	}
+	selectors := make(map[string]*metav1.LabelSelector)
+	for _, pdb := range pdbs {
+		selectors[pdb.Name] = pdb.Spec.Selector
+	}
+	if len(selectors) != 2 {
+		t.Fatalf("Expected 2 budgets but got %v", selectors)
+	}
+	if s := selectors["web"]; s == nil || s.MatchLabels["app"] != "web" {
+		t.Errorf("Expected the selector to be converted but got %v", s)
+	}
+	if s := selectors["empty"]; s != nil {
+		t.Errorf("Expected an empty v1beta1 selector to select no pods but got %v", s)
+	}
+}
+
+func TestSortPods(t *testing.T) {
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "a"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "b"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "a"}},
+	}
+	SortPods(pods)
+	var got []string
+	for _, p := range pods {
+		got = append(got, p.Namespace+"/"+p.Name)
+	}
+	if want := "a/a a/b b/a"; fmt.Sprint(got) != "["+want+"]" {
+		t.Errorf("Expected [%v] but got %v", want, got)
+	}
+}
@@ -1,20 +1,62 @@
 package internal
 
-// Check is a configured check ready to be added to a runner.
-type Check interface {
-	AddtoRunner(*Runner)
-}
+import (
+	"fmt"
+	"path"
+	"time"
+)
 
 // CheckConfig defines a Check.
 type CheckConfig struct {
-	Name        string            `yaml:"name"`
-	Description string            `yaml:"description"`
-	Kind        string            `yaml:"kind"`
-	Spec        map[string]string `yaml:"spec"`
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Kind        string                 `yaml:"kind"`
+	Timeout     time.Duration          `yaml:"timeout"`
+	Severity    string                 `yaml:"severity"`
+	Threshold   float64                `yaml:"threshold"`
+	Weight      *int                   `yaml:"weight"`
+	Scope       ScopeConfig            `yaml:"scope"`
+	Spec        map[string]interface{} `yaml:"spec"`
+}
+
+// CheckName returns the name the check reports its results under, which is the last element of its kind.
+func (cfg CheckConfig) CheckName() string {
+	return path.Base(cfg.Kind)
+}
+
+// Validate checks the settings common to every kind of check.
+func (cfg CheckConfig) Validate() error {
+	if _, ok := severityWeights[cfg.severity()]; !ok {
+		return fmt.Errorf("unknown severity %q, expected one of %s, %s or %s", cfg.Severity, SeverityInfo, SeverityWarn, SeverityCritical)
+	}
+	if cfg.Threshold < 0 || cfg.Threshold > 100 {
+		return fmt.Errorf("threshold must be a percentage between 0 and 100, got %v", cfg.Threshold)
+	}
+	if cfg.Weight != nil && *cfg.Weight < 0 {
+		return fmt.Errorf("weight must not be negative, got %d", *cfg.Weight)
+	}
+	return nil
+}
+
+// severity returns the severity of the check, which defaults to critical.
+func (cfg CheckConfig) severity() string {
+	if cfg.Severity == "" {
+		return SeverityCritical
+	}
+	return cfg.Severity
+}
+
+// weight returns the weight of the check in scores, which defaults to the weight of its severity.
+func (cfg CheckConfig) weight() int {
+	if cfg.Weight != nil {
+		return *cfg.Weight
+	}
+	return severityWeights[cfg.severity()]
 }
 
 // ReliabilityConfig defines an overall configuration for the Reliability Scanner.
 type ReliabilityConfig struct {
+	Scope  ScopeConfig   `yaml:"scope"`
 	Checks []CheckConfig `yaml:"checks"`
 }
 
@@ -0,0 +1,98 @@
+// Package checktest runs checks against a fake cluster in tests.
+package checktest
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/reliability-scanner/internal"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// Cluster is the fake cluster a check is run against.
+type Cluster struct {
+	// Objects are served by the typed client and the Cache.
+	Objects []runtime.Object
+	// Client replaces the fake client for Objects if set.
+	Client kubernetes.Interface
+	// Dynamic are unstructured objects served by the dynamic client, along with the list kind of each resource.
+	Dynamic   []runtime.Object
+	ListKinds map[schema.GroupVersionResource]string
+	// RestConfig is the configuration of the cluster's API server.
+	RestConfig *rest.Config
+	// Scope is the global scope of the scan.
+	Scope internal.ScopeConfig
+}
+
+// Run configures the check, runs it against the cluster and returns the ReportItem it sends
+// before it is evaluated against its threshold.
+func Run(t *testing.T, cfg internal.CheckConfig, cluster Cluster) internal.ReportItem {
+	t.Helper()
+	querier, err := internal.NewQuerier(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error configuring check: %v", err)
+	}
+	def, _ := internal.LookupCheck(cfg.Kind)
+
+	client := cluster.Client
+	if client == nil {
+		client = fake.NewSimpleClientset(cluster.Objects...)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := internal.NewCache(client)
+	cache.Register(def.Resources...)
+	if err := cache.Sync(ctx, time.Minute); err != nil {
+		t.Fatalf("Unexpected error syncing cache: %v", err)
+	}
+	scope, err := internal.NewScope(cluster.Scope, cfg, cache)
+	if err != nil {
+		t.Fatalf("Unexpected error configuring scope: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	results := make(chan internal.ReportItem, 1)
+	querier.Start(&internal.QuerierConfig{
+		Context:       ctx,
+		RestConfig:    cluster.RestConfig,
+		Client:        client,
+		DynamicClient: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), cluster.ListKinds, cluster.Dynamic...),
+		Cache:         cache,
+		Scope:         scope,
+		Results:       results,
+		Logger:        logger,
+		Complete:      make(chan struct{}),
+	})
+	select {
+	case item := <-results:
+		return item
+	default:
+		t.Fatal("Check completed without a result")
+		return internal.ReportItem{}
+	}
+}
+
+// Controller returns an ownerReference to the controller of an object.
+func Controller(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+// Statuses returns the status of each of the item's results by name, for comparing in tests.
+func Statuses(item internal.ReportItem) map[string]string {
+	out := make(map[string]string)
+	for _, i := range item.Items {
+		out[i.Name] = i.Status
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CheckDefinition describes a kind of check that can be configured.
+type CheckDefinition struct {
+	// Kind is the kind used to reference the check in configuration, e.g. v1alpha1/pod/qos.
+	Kind        string
+	Description string
+	// NewSpec returns a pointer to a spec for the check populated with its defaults.
+	NewSpec func() interface{}
+	// New returns a Querier configured with a spec returned by NewSpec.
+	New func(spec interface{}) (Querier, error)
+	// Resources are read by the check from the Cache. Only the resources of configured checks are cached.
+	Resources []Resource
+}
+
+// SpecValidator may be implemented by a spec to reject invalid configuration.
+type SpecValidator interface {
+	Validate() error
+}
+
+// SpecField describes a single field of a check's spec.
+type SpecField struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]CheckDefinition)
+)
+
+// Register makes a check kind available to be configured. It panics if the kind is registered twice.
+func Register(def CheckDefinition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[def.Kind]; exists {
+		panic(fmt.Sprintf("check kind %s registered twice", def.Kind))
+	}
+	registry[def.Kind] = def
+}
+
+// LookupCheck returns the definition for the kind, if it has been registered.
+func LookupCheck(kind string) (CheckDefinition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	def, ok := registry[kind]
+	return def, ok
+}
+
+// RegisteredChecks returns every registered check definition sorted by kind.
+func RegisteredChecks() []CheckDefinition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]CheckDefinition, 0, len(registry))
+	for _, def := range registry {
+		out = append(out, def)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// NewQuerier returns the Querier for the configuration, decoding its spec into the typed spec of the
+// registered kind. Unknown kinds, unknown spec fields and invalid values are errors.
+func NewQuerier(cfg CheckConfig) (Querier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	def, ok := LookupCheck(cfg.Kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown check kind %q", cfg.Kind)
+	}
+	spec, err := def.DecodeSpec(cfg.Spec)
+	if err != nil {
+		return nil, err
+	}
+	return def.New(spec)
+}
+
+// DecodeSpec decodes the raw spec on top of the defaults for the check.
+func (def CheckDefinition) DecodeSpec(raw map[string]interface{}) (interface{}, error) {
+	spec := def.NewSpec()
+	if len(raw) > 0 {
+		out, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(out, spec); err != nil {
+			return nil, fmt.Errorf("invalid spec: %v", err)
+		}
+	}
+	if v, ok := spec.(SpecValidator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid spec: %v", err)
+		}
+	}
+	return spec, nil
+}
+
+// Fields describes the fields of the check's spec using their yaml and description struct tags.
+func (def CheckDefinition) Fields() []SpecField {
+	v := reflect.Indirect(reflect.ValueOf(def.NewSpec()))
+	var fields []SpecField
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		field := SpecField{
+			Name:        name,
+			Type:        specFieldType(f.Type),
+			Description: f.Tag.Get("description"),
+		}
+		if !v.Field(i).IsZero() || f.Type.Kind() == reflect.Bool {
+			field.Default = fmt.Sprint(v.Field(i).Interface())
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func specFieldType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return specFieldType(t.Elem())
+	case reflect.Slice:
+		return "[]" + specFieldType(t.Elem())
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", specFieldType(t.Key()), specFieldType(t.Elem()))
+	default:
+		return t.Kind().String()
+	}
+}
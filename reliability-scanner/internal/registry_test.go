@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSpec struct {
+	Name     string        `yaml:"name" description:"A name."`
+	Count    int           `yaml:"count" description:"A count."`
+	Enabled  bool          `yaml:"enabled" description:"Whether it is enabled."`
+	Interval time.Duration `yaml:"interval"`
+	Limit    *int          `yaml:"limit"`
+	Tags     []string      `yaml:"tags"`
+	Ignored  string        `yaml:"-"`
+}
+
+func (spec *testSpec) Validate() error {
+	if spec.Count < 0 {
+		return errors.New("count must not be negative")
+	}
+	return nil
+}
+
+var testDefinition = CheckDefinition{
+	Kind: "test/registry",
+	NewSpec: func() interface{} {
+		return &testSpec{Name: "default", Count: 1}
+	},
+	New: func(spec interface{}) (Querier, error) {
+		return nil, nil
+	},
+}
+
+func init() {
+	Register(testDefinition)
+}
+
+func TestDecodeSpec(t *testing.T) {
+	limit := 2
+	testcases := []struct {
+		desc      string
+		raw       map[string]interface{}
+		want      *testSpec
+		expectErr string
+	}{
+		{
+			desc: "Defaults are used without a spec",
+			want: &testSpec{Name: "default", Count: 1},
+		}, {
+			desc: "Fields override the defaults",
+			raw:  map[string]interface{}{"count": 3, "enabled": true, "limit": 2, "tags": []string{"a"}},
+			want: &testSpec{Name: "default", Count: 3, Enabled: true, Limit: &limit, Tags: []string{"a"}},
+		}, {
+			desc:      "Unknown fields are rejected",
+			raw:       map[string]interface{}{"cuont": 3},
+			expectErr: "invalid spec: yaml: unmarshal errors:\n  line 1: field cuont not found in type internal.testSpec",
+		}, {
+			desc:      "Invalid values are rejected",
+			raw:       map[string]interface{}{"count": "three"},
+			expectErr: "invalid spec: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `three` into int",
+		}, {
+			desc:      "Specs are validated",
+			raw:       map[string]interface{}{"count": -1},
+			expectErr: "invalid spec: count must not be negative",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := testDefinition.DecodeSpec(tc.raw)
+			if len(tc.expectErr) > 0 {
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatalf("Expected error %q but got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %+v but got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	want := []SpecField{
+		{Name: "name", Type: "string", Default: "default", Description: "A name."},
+		{Name: "count", Type: "int", Default: "1", Description: "A count."},
+		{Name: "enabled", Type: "bool", Default: "false", Description: "Whether it is enabled."},
+		{Name: "interval", Type: "duration"},
+		{Name: "limit", Type: "int"},
+		{Name: "tags", Type: "[]string"},
+	}
+	if got := testDefinition.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v but got %+v", want, got)
+	}
+}
+
+func TestNewQuerier(t *testing.T) {
+	testcases := []struct {
+		desc      string
+		cfg       CheckConfig
+		expectErr string
+	}{
+		{
+			desc: "Registered kinds are configured",
+			cfg:  CheckConfig{Kind: "test/registry"},
+		}, {
+			desc:      "Unknown kinds are rejected",
+			cfg:       CheckConfig{Kind: "test/unknown"},
+			expectErr: `unknown check kind "test/unknown"`,
+		}, {
+			desc:      "Specs are decoded",
+			cfg:       CheckConfig{Kind: "test/registry", Spec: map[string]interface{}{"count": -1}},
+			expectErr: "invalid spec: count must not be negative",
+		}, {
+			desc:      "Common settings are validated",
+			cfg:       CheckConfig{Kind: "test/registry", Severity: "fatal"},
+			expectErr: `unknown severity "fatal", expected one of info, warn or critical`,
+		}, {
+			desc:      "Thresholds are percentages",
+			cfg:       CheckConfig{Kind: "test/registry", Threshold: 101},
+			expectErr: "threshold must be a percentage between 0 and 100, got 101",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewQuerier(tc.cfg)
+			switch {
+			case err == nil && len(tc.expectErr) > 0:
+				t.Errorf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && err.Error() != tc.expectErr:
+				t.Errorf("Expected error %q but got %q", tc.expectErr, err)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a kind twice to panic")
+		}
+	}()
+	Register(testDefinition)
+}
@@ -3,16 +3,27 @@ package internal
 import (
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+// Statuses of Items, ReportItems and Reports. An item which could not be checked, e.g. because its
+// check timed out, is an error rather than a failure. An Item with a warning counts as passed.
+const (
+	StatusPassed  = "passed"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+	StatusError   = "error"
+)
+
 // Item defines an Item within a ReportItem
 type Item struct {
-	Name    string                 `yaml:"name"`
-	Status  string                 `yaml:"status"`
-	Details map[string]interface{} `yaml:"details"`
+	Name      string                 `yaml:"name"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Status    string                 `yaml:"status"`
+	Details   map[string]interface{} `yaml:"details"`
 }
 
 // ReportItem defines a set of Items
@@ -20,10 +31,25 @@ type ReportItem struct {
 	Name   string `yaml:"name"`
 	Status string `yaml:"status"`
 	Meta   struct {
-		File string `yaml:"file"`
-		Type string `yaml:"type"`
+		File      string  `yaml:"file"`
+		Type      string  `yaml:"type"`
+		Severity  string  `yaml:"severity"`
+		Weight    int     `yaml:"weight"`
+		Threshold float64 `yaml:"threshold"`
+		Score     float64 `yaml:"score"`
 	} `yaml:"meta"`
-	Items []Item `yaml:"items"`
+	Items      []Item      `yaml:"items"`
+	Exemptions []Exemption `yaml:"exemptions,omitempty"`
+}
+
+// AddExemption records an exemption, once per exempt object.
+func (item *ReportItem) AddExemption(e Exemption) {
+	for _, existing := range item.Exemptions {
+		if existing.Name == e.Name {
+			return
+		}
+	}
+	item.Exemptions = append(item.Exemptions, e)
 }
 
 // Report is the overall output of the program to be returned to Sonobuoy
@@ -31,7 +57,10 @@ type Report struct {
 	Name   string `yaml:"name"`
 	Status string `yaml:"status"`
 	Meta   struct {
-		Type string `yaml:"type"`
+		Type       string             `yaml:"type"`
+		Score      float64            `yaml:"score"`
+		Grade      string             `yaml:"grade"`
+		Namespaces map[string]float64 `yaml:"namespaces,omitempty"`
 	} `yaml:"meta"`
 	Items []ReportItem
 }
@@ -45,27 +74,33 @@ func (runner *Runner) BuildReport(cc int, name string) *Report {
 
 	report := Report{
 		Name:   name,
-		Status: "passed",
+		Status: StatusPassed,
 	}
 	for len(report.Items) < cc {
 		item := <-runner.Results
-		for _, check := range item.Items {
-			if check.Status != "passed" {
-				item.Status = "failed"
-			}
-		}
-		if item.Status != "passed" {
-			report.Status = "failed"
+		if item.Status != StatusPassed && item.Meta.Severity == SeverityCritical {
+			report.Status = StatusFailed
 		}
 		report.Items = append(report.Items, item)
 	}
+	report.Meta.Score = weightedScore(report.Items)
+	report.Meta.Grade = Grade(report.Meta.Score)
+	report.Meta.Namespaces = namespaceScores(report.Items)
 	return &report
 }
 
-// WriteReport writes a Report out to a path on disk
+// WriteReport writes a Report out to a path on disk and signals Sonobuoy that it is done
 func (runner *Runner) WriteReport(report *Report, path string) error {
-	signalPath := path + "/done"
-	resultsPath := path + "/reliability.yaml"
+	resultsPath, err := runner.WriteResults(report, path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+"/done", []byte(resultsPath), 0644)
+}
+
+// WriteResults writes a Report out to reliability.yaml in a directory, returning its path
+func (runner *Runner) WriteResults(report *Report, path string) (string, error) {
+	resultsPath := filepath.Join(path, "reliability.yaml")
 	runner.Logger.WithFields(log.Fields{
 		"component": "runner",
 		"phase":     "report",
@@ -73,16 +108,17 @@ func (runner *Runner) WriteReport(report *Report, path string) error {
 
 	out, err := yaml.Marshal(report)
 	if err != nil {
-		return err
+		return "", err
 	}
+	return resultsPath, ioutil.WriteFile(resultsPath, out, 0644)
+}
 
-	err = ioutil.WriteFile(resultsPath, out, 0644)
-	if err != nil {
-		return err
-	}
-	err = ioutil.WriteFile(signalPath, []byte(resultsPath), 0644)
-	if err != nil {
-		return err
+// Errored returns whether any check in the Report could not complete.
+func (report *Report) Errored() bool {
+	for _, item := range report.Items {
+		if item.Status == StatusError {
+			return true
+		}
 	}
-	return nil
+	return false
 }
@@ -2,9 +2,14 @@ package internal
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 var (
@@ -13,12 +18,20 @@ var (
 	CheckCompleteMsg string = "complete"
 )
 
+// DefaultCheckTimeout is how long a check may run for when its configuration sets no timeout.
+var DefaultCheckTimeout = 10 * time.Minute
+
 // QuerierConfig provides generic configuration options for a Querier
 type QuerierConfig struct {
-	Context  context.Context
-	Results  chan ReportItem
-	Logger   *log.Logger
-	Complete chan struct{}
+	Context       context.Context
+	RestConfig    *rest.Config
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Cache         *Cache
+	Scope         *Scope
+	Results       chan ReportItem
+	Logger        *log.Logger
+	Complete      chan struct{}
 }
 
 // Querier is a gather of information that can be ran by the Runner.
@@ -26,17 +39,44 @@ type Querier interface {
 	Start(*QuerierConfig)
 }
 
+// ConfiguredQuerier is a Querier along with the configuration of its check and the resources it
+// reads from the Cache.
+type ConfiguredQuerier struct {
+	Config    CheckConfig
+	Scope     *Scope
+	Querier   Querier
+	Resources []Resource
+}
+
 // Runner runs the included Queriers, based on the configured Checks provided.
 type Runner struct {
-	Config   *ReliabilityConfig
-	Context  context.Context
-	Queriers []Querier
-	Results  chan ReportItem
-	Complete chan struct{}
-	Logger   *log.Logger
+	Config        *ReliabilityConfig
+	Context       context.Context
+	RestConfig    *rest.Config
+	Client        kubernetes.Interface
+	DynamicClient dynamic.Interface
+	Cache         *Cache
+	Queriers      []ConfiguredQuerier
+	Results       chan ReportItem
+	Complete      chan struct{}
+	Logger        *log.Logger
 }
 
-// Run runs the Runner
+// AddQuerier configures the runner with the Querier for a check and registers the resources it
+// reads with the Cache.
+func (runner *Runner) AddQuerier(querier ConfiguredQuerier) {
+	runner.Queriers = append(runner.Queriers, querier)
+	if runner.Cache != nil {
+		runner.Cache.Register(querier.Resources...)
+	}
+	runner.Logger.WithFields(log.Fields{
+		"check_name": querier.Config.CheckName(),
+		"phase":      "add",
+	}).Info("complete")
+}
+
+// Run runs the Runner. Each Querier sends exactly one ReportItem to the Results channel, even
+// if it times out or panics.
 func (runner Runner) Run() {
 	if len(runner.Config.Checks) < 1 {
 		runner.Logger.WithFields(log.Fields{
@@ -52,11 +92,97 @@ func (runner Runner) Run() {
 	}).Info("waiting for checks to complete")
 
 	for _, querier := range runner.Queriers {
-		go querier.Start(&QuerierConfig{
-			Context:  runner.Context,
-			Results:  runner.Results,
-			Logger:   runner.Logger,
-			Complete: make(chan struct{}),
+		go runner.start(querier)
+	}
+}
+
+// start runs a Querier until it sends its result, returns, panics or times out. In all but the
+// first case an error ReportItem is sent in place of its result, as it is if the resources the
+// Querier reads are not in the Cache.
+func (runner Runner) start(querier ConfiguredQuerier) {
+	if err := runner.Cache.Err(querier.Resources...); err != nil {
+		runner.Logger.WithFields(log.Fields{
+			"component":  "runner",
+			"check_name": querier.Config.CheckName(),
+			"phase":      "run",
+		}).Error(err)
+		runner.Results <- evaluate(querier.Config, ErrorReportItem(querier.Config, err))
+		return
+	}
+
+	timeout := querier.Config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(runner.Context, timeout)
+	defer cancel()
+
+	// Results is buffered so that a Querier which completes after timing out does not block forever.
+	results := make(chan ReportItem, 1)
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		querier.Querier.Start(&QuerierConfig{
+			Context:       ctx,
+			RestConfig:    runner.RestConfig,
+			Client:        runner.Client,
+			DynamicClient: runner.DynamicClient,
+			Cache:         runner.Cache,
+			Scope:         querier.Scope,
+			Results:       results,
+			Logger:        runner.Logger,
+			Complete:      make(chan struct{}),
 		})
+		done <- nil
+	}()
+
+	var err error
+	select {
+	case item := <-results:
+		runner.Results <- evaluate(querier.Config, item)
+		return
+	case err = <-done:
+		// A Querier sends its result before returning, so prefer it if there is one.
+		select {
+		case item := <-results:
+			runner.Results <- evaluate(querier.Config, item)
+			return
+		default:
+		}
+		if err == nil {
+			err = fmt.Errorf("check completed without a result")
+		}
+	case <-ctx.Done():
+		err = fmt.Errorf("check did not complete within %v: %v", timeout, ctx.Err())
+	}
+
+	runner.Logger.WithFields(log.Fields{
+		"component":  "runner",
+		"check_name": querier.Config.CheckName(),
+		"phase":      "run",
+	}).Error(err)
+	runner.Results <- evaluate(querier.Config, ErrorReportItem(querier.Config, err))
+}
+
+// ErrorReportItem returns a ReportItem for a check which could not complete.
+func ErrorReportItem(cfg CheckConfig, err error) ReportItem {
+	name := cfg.Name
+	if name == "" {
+		name = cfg.CheckName()
+	}
+	return ReportItem{
+		Name:   cfg.CheckName(),
+		Status: StatusError,
+		Items: []Item{{
+			Name:   name,
+			Status: StatusError,
+			Details: map[string]interface{}{
+				"error": err.Error(),
+			},
+		}},
 	}
 }
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// ExemptAnnotation opts a workload, pod or namespace out of the listed checks, e.g.
+	// `reliability.sonobuoy.io/exempt: probes,qos`. A value of `*` opts out of every check.
+	ExemptAnnotation = "reliability.sonobuoy.io/exempt"
+	// ExemptReasonAnnotation records why an object is exempt.
+	ExemptReasonAnnotation = "reliability.sonobuoy.io/exempt-reason"
+)
+
+// SystemNamespaces are excluded from every check unless system namespaces are included, or
+// they are listed explicitly.
+var SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease", "sonobuoy"}
+
+// ScopeConfig selects the objects a check applies to. It may be set for the whole scan and for
+// each check, where every field set on the check replaces the field of the scan.
+type ScopeConfig struct {
+	Namespaces              []string `yaml:"namespaces" mapstructure:"namespaces"`
+	ExcludeNamespaces       []string `yaml:"exclude_namespaces" mapstructure:"exclude_namespaces"`
+	IncludeSystemNamespaces *bool    `yaml:"include_system_namespaces" mapstructure:"include_system_namespaces"`
+	NamespaceSelector       string   `yaml:"namespace_selector" mapstructure:"namespace_selector"`
+	LabelSelector           string   `yaml:"label_selector" mapstructure:"label_selector"`
+}
+
+// merge returns the configuration with the fields set in override replaced.
+func (cfg ScopeConfig) merge(override ScopeConfig) ScopeConfig {
+	if override.Namespaces != nil {
+		cfg.Namespaces = override.Namespaces
+	}
+	if override.ExcludeNamespaces != nil {
+		cfg.ExcludeNamespaces = override.ExcludeNamespaces
+	}
+	if override.IncludeSystemNamespaces != nil {
+		cfg.IncludeSystemNamespaces = override.IncludeSystemNamespaces
+	}
+	if override.NamespaceSelector != "" {
+		cfg.NamespaceSelector = override.NamespaceSelector
+	}
+	if override.LabelSelector != "" {
+		cfg.LabelSelector = override.LabelSelector
+	}
+	return cfg
+}
+
+// Exemption records an object which was not checked because of its ExemptAnnotation.
+type Exemption struct {
+	Name   string `yaml:"name"`
+	Source string `yaml:"source"`
+	Reason string `yaml:"reason"`
+}
+
+// Scope decides whether objects are checked by a single check. A nil Scope includes everything.
+type Scope struct {
+	check             string
+	cache             *Cache
+	namespaces        map[string]bool
+	excluded          map[string]bool
+	includeSystem     bool
+	namespaceSelector labels.Selector
+	labelSelector     labels.Selector
+}
+
+// NewScope returns the Scope of the check from the scan's scope and the check's own.
+func NewScope(global ScopeConfig, check CheckConfig, cache *Cache) (*Scope, error) {
+	cfg := global.merge(check.Scope)
+	scope := &Scope{
+		check:         check.CheckName(),
+		cache:         cache,
+		namespaces:    toSet(cfg.Namespaces),
+		excluded:      toSet(cfg.ExcludeNamespaces),
+		includeSystem: cfg.IncludeSystemNamespaces != nil && *cfg.IncludeSystemNamespaces,
+	}
+	var err error
+	if scope.namespaceSelector, err = labels.Parse(cfg.NamespaceSelector); err != nil {
+		return nil, fmt.Errorf("invalid namespace_selector: %v", err)
+	}
+	if scope.labelSelector, err = labels.Parse(cfg.LabelSelector); err != nil {
+		return nil, fmt.Errorf("invalid label_selector: %v", err)
+	}
+	return scope, nil
+}
+
+func toSet(values []string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		out[v] = true
+	}
+	return out
+}
+
+// InNamespace returns whether objects in the namespace are in scope.
+func (s *Scope) InNamespace(namespace string) bool {
+	if s == nil {
+		return true
+	}
+	if len(s.namespaces) > 0 && !s.namespaces[namespace] {
+		return false
+	}
+	if s.excluded[namespace] {
+		return false
+	}
+	if !s.includeSystem && !s.namespaces[namespace] {
+		for _, ns := range SystemNamespaces {
+			if ns == namespace {
+				return false
+			}
+		}
+	}
+	if s.namespaceSelector.Empty() {
+		return true
+	}
+	var nsLabels map[string]string
+	if ns, err := s.cache.Namespaces.Get(namespace); err == nil {
+		nsLabels = ns.Labels
+	}
+	return s.namespaceSelector.Matches(labels.Set(nsLabels))
+}
+
+// Pods returns the pods which are in scope. The scanner's own pods are never in scope.
+func (s *Scope) Pods(pods []*corev1.Pod) []*corev1.Pod {
+	var out []*corev1.Pod
+	for _, pod := range pods {
+		if IsSonobouyPod(pod.Name) {
+			continue
+		}
+		if s == nil || s.InNamespace(pod.Namespace) && s.labelSelector.Matches(labels.Set(pod.Labels)) {
+			out = append(out, pod)
+		}
+	}
+	return out
+}
+
+// Namespaces returns the namespaces which are in scope.
+func (s *Scope) Namespaces(namespaces []*corev1.Namespace) []*corev1.Namespace {
+	if s == nil {
+		return namespaces
+	}
+	var out []*corev1.Namespace
+	for _, ns := range namespaces {
+		if s.InNamespace(ns.Name) && s.labelSelector.Matches(labels.Set(ns.Labels)) {
+			out = append(out, ns)
+		}
+	}
+	return out
+}
+
+// Exempt returns the Exemption of the object of the kind from the check, if it has one.
+func (s *Scope) Exempt(name, kind string, obj metav1.Object) (Exemption, bool) {
+	if s == nil {
+		return Exemption{}, false
+	}
+	value, ok := obj.GetAnnotations()[ExemptAnnotation]
+	if !ok {
+		return Exemption{}, false
+	}
+	for _, check := range strings.Split(value, ",") {
+		check = strings.TrimSpace(check)
+		if check == "*" || check == s.check {
+			return Exemption{
+				Name:   name,
+				Source: objectRef(kind, obj),
+				Reason: obj.GetAnnotations()[ExemptReasonAnnotation],
+			}, true
+		}
+	}
+	return Exemption{}, false
+}
+
+// ExemptPod returns the Exemption of the pod from the check, if it, one of its controllers or
+// its namespace is exempt. The exemption is named after the pod's workload.
+func (s *Scope) ExemptPod(pod *corev1.Pod) (Exemption, bool) {
+	if s == nil {
+		return Exemption{}, false
+	}
+	name := s.cache.WorkloadFor(pod).String()
+	objects := append([]kindedObject{{"Pod", pod}}, s.cache.controllers(pod)...)
+	if ns, err := s.cache.Namespaces.Get(pod.Namespace); err == nil {
+		objects = append(objects, kindedObject{"Namespace", ns})
+	}
+	for _, o := range objects {
+		if e, ok := s.Exempt(name, o.kind, o.obj); ok {
+			return e, true
+		}
+	}
+	return Exemption{}, false
+}
+
+func objectRef(kind string, obj metav1.Object) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s/%s", strings.ToLower(kind), obj.GetName())
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetNamespace(), strings.ToLower(kind), obj.GetName())
+}
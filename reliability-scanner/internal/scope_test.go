@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScopePods(t *testing.T) {
+	c := syncedCache(t, fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"env": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "b"}},
+	))
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "web", Labels: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "db", Labels: map[string]string{"app": "db"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "dns"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "sonobuoy", Name: "sonobuoy-reliability-scanner-job-1234"}},
+	}
+	include := true
+
+	testcases := []struct {
+		desc      string
+		global    ScopeConfig
+		check     ScopeConfig
+		want      []string
+		expectErr string
+	}{
+		{
+			desc: "System namespaces are excluded by default",
+			want: []string{"a/web", "b/db"},
+		}, {
+			desc:   "System namespaces may be included",
+			global: ScopeConfig{IncludeSystemNamespaces: &include},
+			want:   []string{"a/web", "b/db", "kube-system/dns"},
+		}, {
+			desc:   "Listed system namespaces are included",
+			global: ScopeConfig{Namespaces: []string{"a", "kube-system"}},
+			want:   []string{"a/web", "kube-system/dns"},
+		}, {
+			desc:   "Namespaces may be excluded",
+			global: ScopeConfig{ExcludeNamespaces: []string{"a"}},
+			want:   []string{"b/db"},
+		}, {
+			desc:   "Namespaces may be selected by label",
+			global: ScopeConfig{NamespaceSelector: "env=prod"},
+			want:   []string{"a/web"},
+		}, {
+			desc:   "Pods may be selected by label",
+			global: ScopeConfig{LabelSelector: "app=db"},
+			want:   []string{"b/db"},
+		}, {
+			desc:   "The check's scope replaces the fields it sets",
+			global: ScopeConfig{Namespaces: []string{"a"}, LabelSelector: "app=db"},
+			check:  ScopeConfig{Namespaces: []string{"b"}},
+			want:   []string{"b/db"},
+		}, {
+			desc:      "Invalid selectors are rejected",
+			global:    ScopeConfig{LabelSelector: "app in"},
+			expectErr: "invalid label_selector",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			scope, err := NewScope(tc.global, CheckConfig{Kind: "v1alpha1/pod/probes", Scope: tc.check}, c)
+			if len(tc.expectErr) > 0 {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.expectErr) {
+					t.Fatalf("Expected error %q but got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var got []string
+			for _, p := range scope.Pods(pods) {
+				got = append(got, p.Namespace+"/"+p.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScopeExemptPod(t *testing.T) {
+	exempt := func(value string) map[string]string {
+		return map[string]string{ExemptAnnotation: value, ExemptReasonAnnotation: "testing"}
+	}
+	c := syncedCache(t, fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sandbox", Annotations: exempt("*")}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app", Annotations: exempt("qos, probes")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-1234", Namespace: "app", OwnerReferences: controlledBy("Deployment", "web")}},
+	), ResourcePods)
+	scope, err := NewScope(ScopeConfig{}, CheckConfig{Kind: "v1alpha1/pod/probes"}, c)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		desc string
+		pod  *corev1.Pod
+		want *Exemption
+	}{
+		{
+			desc: "Pods are not exempt by default",
+			pod:  testPod("p", nil),
+		}, {
+			desc: "Pods may be exempt",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "app", Annotations: exempt("probes")}},
+			want: &Exemption{Name: "app/pod/p", Source: "app/pod/p", Reason: "testing"},
+		}, {
+			desc: "Pods exempt from other checks are checked",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "app", Annotations: exempt("qos")}},
+		}, {
+			desc: "Controllers exempt their pods",
+			pod:  testPod("web-1234-abcd", controlledBy("ReplicaSet", "web-1234")),
+			want: &Exemption{Name: "app/deployment/web", Source: "app/deployment/web", Reason: "testing"},
+		}, {
+			desc: "Namespaces exempt their pods",
+			pod:  &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "sandbox"}},
+			want: &Exemption{Name: "sandbox/pod/p", Source: "namespace/sandbox", Reason: "testing"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, ok := scope.ExemptPod(tc.pod)
+			switch {
+			case tc.want == nil && ok:
+				t.Errorf("Expected no exemption but got %v", got)
+			case tc.want != nil && !ok:
+				t.Errorf("Expected exemption %v but got none", *tc.want)
+			case tc.want != nil && got != *tc.want:
+				t.Errorf("Expected exemption %v but got %v", *tc.want, got)
+			}
+		})
+	}
+
+	var nilScope *Scope
+	if _, ok := nilScope.ExemptPod(testPod("p", nil)); ok {
+		t.Errorf("Expected a nil Scope to exempt nothing")
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"math"
+)
+
+// Severities of checks. Only failing critical checks fail the Report, while every check
+// contributes to its score in proportion to its weight.
+const (
+	SeverityInfo     = "info"
+	SeverityWarn     = "warn"
+	SeverityCritical = "critical"
+)
+
+// severityWeights are the default weights of checks by severity.
+var severityWeights = map[string]int{
+	SeverityInfo:     1,
+	SeverityWarn:     2,
+	SeverityCritical: 3,
+}
+
+// grades are the minimum scores for each grade, from best to worst.
+var grades = []struct {
+	grade    string
+	minScore float64
+}{
+	{"A", 90},
+	{"B", 80},
+	{"C", 70},
+	{"D", 60},
+	{"F", 0},
+}
+
+// Grade returns the letter grade for a score out of 100.
+func Grade(score float64) string {
+	for _, g := range grades {
+		if score >= g.minScore {
+			return g.grade
+		}
+	}
+	return grades[len(grades)-1].grade
+}
+
+// evaluate sets the severity, weight and score of a check's ReportItem from its configuration. The
+// check fails if the percentage of failing Items is above its threshold, or if the check itself
+// failed, e.g. because it could not list the cluster. A check which errored scores zero.
+func evaluate(cfg CheckConfig, item ReportItem) ReportItem {
+	item.Meta.Severity = cfg.severity()
+	item.Meta.Weight = cfg.weight()
+	item.Meta.Threshold = cfg.Threshold
+	if item.Status == StatusError {
+		item.Meta.Score = 0
+		return item
+	}
+
+	passed := 0
+	for _, i := range item.Items {
+		if i.Status == StatusPassed || i.Status == StatusWarning {
+			passed++
+		}
+	}
+	item.Meta.Score = score(passed, len(item.Items))
+	if item.Status == StatusFailed && len(item.Items) == 0 {
+		item.Meta.Score = 0
+	}
+	if item.Status != StatusFailed {
+		item.Status = StatusPassed
+		if 100-item.Meta.Score > cfg.Threshold {
+			item.Status = StatusFailed
+		}
+	}
+	return item
+}
+
+// score returns the percentage of passing items, rounded to one decimal place. Nothing to check
+// scores full marks.
+func score(passed, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return round(100 * float64(passed) / float64(total))
+}
+
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+// weightedScore returns the average score of the checks weighted by their weight.
+func weightedScore(items []ReportItem) float64 {
+	var sum, weights float64
+	for _, item := range items {
+		sum += float64(item.Meta.Weight) * item.Meta.Score
+		weights += float64(item.Meta.Weight)
+	}
+	if weights == 0 {
+		return 100
+	}
+	return round(sum / weights)
+}
+
+// namespaceScores returns the weighted score of each namespace, from the Items in the namespace
+// of each check.
+func namespaceScores(items []ReportItem) map[string]float64 {
+	type tally struct{ sum, weights float64 }
+	tallies := make(map[string]*tally)
+	for _, item := range items {
+		if item.Status == StatusError {
+			continue
+		}
+		passed := make(map[string]int)
+		total := make(map[string]int)
+		for _, i := range item.Items {
+			if i.Namespace == "" {
+				continue
+			}
+			total[i.Namespace]++
+			if i.Status == StatusPassed || i.Status == StatusWarning {
+				passed[i.Namespace]++
+			}
+		}
+		for ns, n := range total {
+			if tallies[ns] == nil {
+				tallies[ns] = &tally{}
+			}
+			weight := float64(item.Meta.Weight)
+			tallies[ns].sum += weight * score(passed[ns], n)
+			tallies[ns].weights += weight
+		}
+	}
+
+	if len(tallies) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(tallies))
+	for ns, t := range tallies {
+		out[ns] = 100
+		if t.weights > 0 {
+			out[ns] = round(t.sum / t.weights)
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"testing"
+)
+
+func items(statuses ...string) []Item {
+	var out []Item
+	for _, s := range statuses {
+		out = append(out, Item{Status: s})
+	}
+	return out
+}
+
+func TestEvaluate(t *testing.T) {
+	weight := 5
+	testcases := []struct {
+		desc         string
+		cfg          CheckConfig
+		item         ReportItem
+		wantStatus   string
+		wantScore    float64
+		wantSeverity string
+		wantWeight   int
+	}{
+		{
+			desc:         "Checks pass when every item passes",
+			item:         ReportItem{Status: StatusPassed, Items: items(StatusPassed, StatusWarning)},
+			wantStatus:   StatusPassed,
+			wantScore:    100,
+			wantSeverity: SeverityCritical,
+			wantWeight:   3,
+		}, {
+			desc:         "Checks fail when failures exceed the threshold",
+			item:         ReportItem{Status: StatusPassed, Items: items(StatusPassed, StatusPassed, StatusFailed)},
+			wantStatus:   StatusFailed,
+			wantScore:    66.7,
+			wantSeverity: SeverityCritical,
+			wantWeight:   3,
+		}, {
+			desc:         "Checks pass when failures are within the threshold",
+			cfg:          CheckConfig{Severity: SeverityWarn, Threshold: 50},
+			item:         ReportItem{Status: StatusPassed, Items: items(StatusPassed, StatusFailed)},
+			wantStatus:   StatusPassed,
+			wantScore:    50,
+			wantSeverity: SeverityWarn,
+			wantWeight:   2,
+		}, {
+			desc:         "Checks with nothing to check score full marks",
+			cfg:          CheckConfig{Severity: SeverityInfo, Weight: &weight},
+			item:         ReportItem{Status: StatusPassed},
+			wantStatus:   StatusPassed,
+			wantScore:    100,
+			wantSeverity: SeverityInfo,
+			wantWeight:   5,
+		}, {
+			desc:         "Failed checks without items score zero",
+			item:         ReportItem{Status: StatusFailed},
+			wantStatus:   StatusFailed,
+			wantScore:    0,
+			wantSeverity: SeverityCritical,
+			wantWeight:   3,
+		}, {
+			desc:         "Errored checks score zero",
+			item:         ReportItem{Status: StatusError, Items: items(StatusPassed)},
+			wantStatus:   StatusError,
+			wantScore:    0,
+			wantSeverity: SeverityCritical,
+			wantWeight:   3,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := evaluate(tc.cfg, tc.item)
+			if got.Status != tc.wantStatus || got.Meta.Score != tc.wantScore ||
+				got.Meta.Severity != tc.wantSeverity || got.Meta.Weight != tc.wantWeight {
+				t.Errorf("Expected %v with score %v, severity %v and weight %v but got %v with score %v, severity %v and weight %v",
+					tc.wantStatus, tc.wantScore, tc.wantSeverity, tc.wantWeight,
+					got.Status, got.Meta.Score, got.Meta.Severity, got.Meta.Weight)
+			}
+		})
+	}
+}
+
+func TestGrade(t *testing.T) {
+	testcases := []struct {
+		score float64
+		want  string
+	}{
+		{100, "A"}, {90, "A"}, {89.9, "B"}, {80, "B"}, {70, "C"}, {60, "D"}, {59.9, "F"}, {0, "F"},
+	}
+	for _, tc := range testcases {
+		if got := Grade(tc.score); got != tc.want {
+			t.Errorf("Expected %v to grade %v but got %v", tc.score, tc.want, got)
+		}
+	}
+}
+
+func TestWeightedScore(t *testing.T) {
+	reportItem := func(weight int, score float64, itemList ...Item) ReportItem {
+		item := ReportItem{Status: StatusPassed, Items: itemList}
+		item.Meta.Weight, item.Meta.Score = weight, score
+		return item
+	}
+	checks := []ReportItem{
+		reportItem(3, 50, Item{Namespace: "a", Status: StatusPassed}, Item{Namespace: "b", Status: StatusFailed}),
+		reportItem(1, 100, Item{Namespace: "a", Status: StatusPassed}),
+	}
+
+	if got := weightedScore(checks); got != 62.5 {
+		t.Errorf("Expected a weighted score of 62.5 but got %v", got)
+	}
+	if got := weightedScore(nil); got != 100 {
+		t.Errorf("Expected no checks to score 100 but got %v", got)
+	}
+	scores := namespaceScores(checks)
+	if scores["a"] != 100 || scores["b"] != 0 || len(scores) != 2 {
+		t.Errorf("Expected namespace scores a=100 and b=0 but got %v", scores)
+	}
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Workload identifies the top-level controller of a set of pods. A pod without a known
+// controller is its own workload.
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String returns the workload as namespace/kind/name, e.g. default/deployment/web.
+func (w Workload) String() string {
+	return fmt.Sprintf("%s/%s/%s", w.Namespace, strings.ToLower(w.Kind), w.Name)
+}
+
+// PodFinding is the result of checking a single pod.
+type PodFinding struct {
+	Pod     *corev1.Pod
+	Status  string
+	Details map[string]interface{}
+}
+
+// WorkloadFor resolves the pod to its workload by following controller ownerReferences, e.g.
+// from a pod to its ReplicaSet and then to the Deployment.
+func (c *Cache) WorkloadFor(pod *corev1.Pod) Workload {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return Workload{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}
+	}
+	w := Workload{Kind: ref.Kind, Namespace: pod.Namespace, Name: ref.Name}
+	switch ref.Kind {
+	case "ReplicaSet":
+		if rs, err := c.ReplicaSets.ReplicaSets(pod.Namespace).Get(ref.Name); err == nil {
+			if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+				w.Kind, w.Name = owner.Kind, owner.Name
+			}
+		}
+	case "Job":
+		if job, err := c.Jobs.Jobs(pod.Namespace).Get(ref.Name); err == nil {
+			if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+				w.Kind, w.Name = owner.Kind, owner.Name
+			}
+		}
+	}
+	return w
+}
+
+// statusSeverity orders the statuses of pods so that a workload takes the status of its worst pod.
+// A pod which could not be checked ranks below one which was found to fail.
+var statusSeverity = map[string]int{
+	StatusPassed:  0,
+	StatusWarning: 1,
+	StatusError:   2,
+	StatusFailed:  3,
+}
+
+// WorkloadItems returns an Item per workload from the findings of its pods, so that a failure
+// shared by every replica is reported once. A workload takes the most severe status of its pods,
+// and is reported with the details of the first pod with that status along with its replica
+// counts. The result of each pod is included when podDetail is set.
+func (c *Cache) WorkloadItems(findings []PodFinding, podDetail bool) []Item {
+	var order []Workload
+	byWorkload := make(map[Workload][]PodFinding)
+	for _, f := range findings {
+		w := c.WorkloadFor(f.Pod)
+		if _, ok := byWorkload[w]; !ok {
+			order = append(order, w)
+		}
+		byWorkload[w] = append(byWorkload[w], f)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].String() < order[j].String() })
+
+	var items []Item
+	for _, w := range order {
+		pods := byWorkload[w]
+		item := Item{
+			Name:      w.String(),
+			Namespace: w.Namespace,
+			Status:    StatusPassed,
+		}
+		details := pods[0].Details
+		affected := 0
+		for _, f := range pods {
+			if f.Status == StatusPassed {
+				continue
+			}
+			if statusSeverity[f.Status] > statusSeverity[item.Status] {
+				item.Status = f.Status
+				details = f.Details
+			}
+			affected++
+		}
+
+		item.Details = make(map[string]interface{})
+		for k, v := range details {
+			item.Details[k] = v
+		}
+		item.Details["kind"] = w.Kind
+		item.Details["replicas"] = len(pods)
+		item.Details["affected_replicas"] = affected
+		if podDetail {
+			perPod := make(map[string]interface{})
+			for _, f := range pods {
+				perPod[f.Pod.Name] = map[string]interface{}{
+					"status":  f.Status,
+					"details": f.Details,
+				}
+			}
+			item.Details["pods"] = perPod
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// kindedObject is an object read from the Cache along with its kind, which typed objects omit.
+type kindedObject struct {
+	kind string
+	obj  metav1.Object
+}
+
+// controllers returns the chain of controllers of the pod which are in the Cache, e.g. its
+// ReplicaSet and then its Deployment.
+func (c *Cache) controllers(pod *corev1.Pod) []kindedObject {
+	var out []kindedObject
+	var obj metav1.Object = pod
+	for {
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil {
+			return out
+		}
+		var err error
+		switch ref.Kind {
+		case "ReplicaSet":
+			obj, err = c.ReplicaSets.ReplicaSets(pod.Namespace).Get(ref.Name)
+		case "Deployment":
+			obj, err = c.Deployments.Deployments(pod.Namespace).Get(ref.Name)
+		case "StatefulSet":
+			obj, err = c.StatefulSets.StatefulSets(pod.Namespace).Get(ref.Name)
+		case "DaemonSet":
+			obj, err = c.DaemonSets.DaemonSets(pod.Namespace).Get(ref.Name)
+		case "Job":
+			obj, err = c.Jobs.Jobs(pod.Namespace).Get(ref.Name)
+		default:
+			return out
+		}
+		if err != nil {
+			return out
+		}
+		out = append(out, kindedObject{kind: ref.Kind, obj: obj})
+	}
+}
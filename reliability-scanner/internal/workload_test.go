@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func controlledBy(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func testPod(name string, owners []metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "app", OwnerReferences: owners}}
+}
+
+func TestWorkloadFor(t *testing.T) {
+	c := syncedCache(t, fake.NewSimpleClientset(
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-1234", Namespace: "app", OwnerReferences: controlledBy("Deployment", "web")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "app"}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "report-1234", Namespace: "app", OwnerReferences: controlledBy("CronJob", "report")}},
+	), ResourcePods)
+
+	testcases := []struct {
+		desc   string
+		owners []metav1.OwnerReference
+		want   string
+	}{
+		{desc: "Pods without a controller are their own workload", want: "app/pod/p"},
+		{desc: "ReplicaSets resolve to their Deployment", owners: controlledBy("ReplicaSet", "web-1234"), want: "app/deployment/web"},
+		{desc: "ReplicaSets without a Deployment are the workload", owners: controlledBy("ReplicaSet", "orphan"), want: "app/replicaset/orphan"},
+		{desc: "Unknown ReplicaSets are the workload", owners: controlledBy("ReplicaSet", "missing"), want: "app/replicaset/missing"},
+		{desc: "Jobs resolve to their CronJob", owners: controlledBy("Job", "report-1234"), want: "app/cronjob/report"},
+		{desc: "Other controllers are the workload", owners: controlledBy("StatefulSet", "db"), want: "app/statefulset/db"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := c.WorkloadFor(testPod("p", tc.owners)).String(); got != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWorkloadItems(t *testing.T) {
+	c := syncedCache(t, fake.NewSimpleClientset(), ResourcePods)
+	failure := map[string]interface{}{"error": "missing probe"}
+	findings := []PodFinding{
+		{Pod: testPod("web-1", controlledBy("StatefulSet", "web")), Status: StatusPassed, Details: map[string]interface{}{}},
+		{Pod: testPod("web-0", controlledBy("StatefulSet", "web")), Status: StatusFailed, Details: failure},
+		{Pod: testPod("db-0", controlledBy("StatefulSet", "db")), Status: StatusPassed, Details: map[string]interface{}{}},
+	}
+
+	t.Run("Pods are grouped by workload and sorted", func(t *testing.T) {
+		want := []Item{
+			{Name: "app/statefulset/db", Namespace: "app", Status: StatusPassed, Details: map[string]interface{}{
+				"kind": "StatefulSet", "replicas": 1, "affected_replicas": 0,
+			}},
+			{Name: "app/statefulset/web", Namespace: "app", Status: StatusFailed, Details: map[string]interface{}{
+				"error": "missing probe", "kind": "StatefulSet", "replicas": 2, "affected_replicas": 1,
+			}},
+		}
+		if got := c.WorkloadItems(findings, false); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("Workloads take the most severe status of their pods", func(t *testing.T) {
+		findings := []PodFinding{
+			{Pod: testPod("web-0", controlledBy("StatefulSet", "web")), Status: StatusWarning, Details: map[string]interface{}{"warning": "no limits"}},
+			{Pod: testPod("web-1", controlledBy("StatefulSet", "web")), Status: StatusFailed, Details: failure},
+			{Pod: testPod("web-2", controlledBy("StatefulSet", "web")), Status: StatusFailed, Details: map[string]interface{}{"error": "crash looping"}},
+		}
+		want := []Item{
+			{Name: "app/statefulset/web", Namespace: "app", Status: StatusFailed, Details: map[string]interface{}{
+				"error": "missing probe", "kind": "StatefulSet", "replicas": 3, "affected_replicas": 3,
+			}},
+		}
+		if got := c.WorkloadItems(findings, false); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v but got %v", want, got)
+		}
+	})
+
+	t.Run("Each pod is included with podDetail", func(t *testing.T) {
+		items := c.WorkloadItems(findings, true)
+		want := map[string]interface{}{
+			"web-0": map[string]interface{}{"status": StatusFailed, "details": failure},
+			"web-1": map[string]interface{}{"status": StatusPassed, "details": map[string]interface{}{}},
+		}
+		if got := items[1].Details["pods"]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v but got %v", want, got)
+		}
+		if _, ok := failure["pods"]; ok {
+			t.Errorf("Expected the pod's details not to be modified")
+		}
+	})
+}
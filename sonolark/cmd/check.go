@@ -0,0 +1,91 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/kube"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/lint"
+)
+
+const (
+	outputJSON = "json"
+	outputText = "text"
+)
+
+type checkInput struct {
+	Filename string
+	Output   string
+}
+
+// NewCmdCheck returns a command which statically checks a script without running it.
+func NewCmdCheck(env map[string]string) *cobra.Command {
+	in := checkInput{}
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Parse and resolve a script without executing it, reporting any problems found",
+		// Execute prints the error, so cobra must not print it as well.
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The arguments have been parsed, so any further error is not a usage error.
+			cmd.SilenceUsage = true
+			predeclared := getStaticLibraryFuncs()
+			predeclared["kube"] = kube.KubeNoop()["kube"]
+
+			result, err := lint.Check(in.Filename, nil, predeclared)
+			if err != nil {
+				return err
+			}
+			if err := printCheckResult(os.Stdout, result, in.Output); err != nil {
+				return err
+			}
+			if result.HasErrors() {
+				return errors.New("script has errors")
+			}
+			return nil
+		},
+		Args: cobra.ExactArgs(0),
+	}
+
+	cmd.Flags().StringVarP(&in.Filename, "file", "f", getDefaultScriptName(env), "The name of the script to check")
+	cmd.Flags().StringVarP(&in.Output, "output", "o", outputJSON, "The output format. One of {json, text}")
+	return cmd
+}
+
+func printCheckResult(w io.Writer, result *lint.Result, format string) error {
+	switch format {
+	case outputJSON:
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+	case outputText:
+		for _, i := range result.Issues {
+			fmt.Fprintf(w, "%v:%v:%v: %v: %v\n", result.File, i.Line, i.Column, i.Severity, i.Message)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	return nil
+}
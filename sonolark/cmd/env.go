@@ -24,7 +24,7 @@ import (
 )
 
 var (
-	envKeys = []string{sonobuoy.EnvKeySonobuoy, sonobuoy.EnvKeySonobuoyConfigDir}
+	envKeys = []string{sonobuoy.EnvKeySonobuoy, sonobuoy.EnvKeySonobuoyConfigDir, sonobuoy.EnvKeySonobuoyResultsDir}
 )
 
 // getEnvs grabs a series of keys of interest and just stores them in a map to pass around to
@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
@@ -55,6 +56,30 @@ func getOutOfClusterConfig(kubeconfigPath string) *rest.Config {
 	return config
 }
 
+// getContextConfig returns the config for the named context in the kubeconfig file.
+func getContextConfig(kubeconfigPath, contextName string) (*rest.Config, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: contextName},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load context %q from %q: %w", contextName, kubeconfigPath, err)
+	}
+
+	config.QPS = float32(rest.DefaultQPS * 10)
+	config.Burst = rest.DefaultBurst * 10
+	return config, nil
+}
+
+// parseContextFlag splits a --context value of the form NAME or NAME=KUBECONFIG, defaulting the
+// kubeconfig path if it isn't given.
+func parseContextFlag(spec, defaultKubeconfigPath string) (name, kubeconfigPath string) {
+	if i := strings.Index(spec, "="); i >= 0 {
+		return spec[:i], spec[i+1:]
+	}
+	return spec, defaultKubeconfigPath
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+const (
+	// abortGracePeriod is how long a script has to notice it has been cancelled before the suite is
+	// completed without it. Scripts only notice cancellation when calling a builtin.
+	abortGracePeriod = 5 * time.Second
+)
+
+// applyLimits sets the timeouts and step budget from the input on the thread's Go context. The
+// returned func must be called to release resources.
+func applyLimits(thread *starlark.Thread, in runInput) context.CancelFunc {
+	cancel := func() {}
+	if in.Timeout > 0 {
+		cancel = shared.SetGoCtxTimeout(thread, in.Timeout, fmt.Sprintf("script exceeded its timeout of %v", in.Timeout))
+	}
+	if in.TestTimeout > 0 {
+		shared.SetGoCtxWithValues(thread, sonobuoy.TestTimeoutCtxKey, in.TestTimeout)
+	}
+	if in.MaxSteps > 0 {
+		shared.SetGoCtxWithValues(thread, shared.StepBudgetCtxKey, &shared.StepBudget{Max: in.MaxSteps})
+	}
+	return cancel
+}
+
+// guardBuiltins wraps each builtin of the predeclared modules so that it checks the script's
+// limits before being called. The kube module is not included since it guards its own builtins,
+// along with those of the modules and objects it returns.
+func guardBuiltins(predeclared starlark.StringDict) starlark.StringDict {
+	guarded := starlark.StringDict{}
+	for name, v := range predeclared {
+		if m, ok := v.(*starlarkstruct.Module); ok {
+			v = shared.GuardModule(m)
+		}
+		guarded[name] = v
+	}
+	return guarded
+}
+
+// runScript executes the script and completes the suite, failing the current test if the script fails.
+// If the suite's context is cancelled (e.g. by a timeout) and the script does not stop in time, the suite
+// is completed without waiting for it.
+func runScript(thread *starlark.Thread, filename string, predeclared starlark.StringDict) error {
+	suiteCtx := shared.GetGoCtx(thread)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- execScript(thread, filename, predeclared)
+	}()
+
+	select {
+	case err := <-errc:
+		sonobuoy.Done(thread)
+		return err
+	case <-suiteCtx.Done():
+	}
+
+	select {
+	case err := <-errc:
+		sonobuoy.Done(thread)
+		return err
+	case <-time.After(abortGracePeriod):
+		reason := shared.CancelReason(suiteCtx)
+		logrus.Errorf("Script did not stop within %v of being cancelled: %v", abortGracePeriod, reason)
+		sonobuoy.Finish(suiteCtx, reason)
+		return errors.New(reason)
+	}
+}
+
+func execScript(thread *starlark.Thread, filename string, predeclared starlark.StringDict) error {
+	_, err := starlark.ExecFile(thread, filename, nil, predeclared)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			sonobuoy.FailTest(thread, evalErr.Backtrace())
+			return errors.New(evalErr.Backtrace())
+		}
+		sonobuoy.FailTest(thread, err.Error())
+		return err
+	}
+	return nil
+}
@@ -18,17 +18,22 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"net/http"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/k14s/starlark-go/starlarkstruct"
 	"github.com/spf13/cobra"
-	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary/overlay"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/assert"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/certs"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/env"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/log"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/parallel"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/params"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/prom"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
 	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
 	"k8s.io/client-go/util/homedir"
@@ -54,6 +59,14 @@ type runInput struct {
 	KubeConfigPath string
 	Filename       string
 	LogLevel       log.LevelFlagType
+	Timeout        time.Duration
+	TestTimeout    time.Duration
+	MaxSteps       uint64
+	Contexts       []string
+	AllowPaths     []string
+	AllowExec      []string
+	TraceAPI       bool
+	ValuesPath     string
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -66,30 +79,42 @@ func getRootCmd(env map[string]string) *cobra.Command {
 
 			thread := &starlark.Thread{}
 			shared.SetGoCtx(thread, context.Background())
+			cancel := applyLimits(thread, in)
+			defer cancel()
 
 			// Automatically start/end suite.
 			sonobuoy.StartSuite(thread, -1)
-			defer sonobuoy.Done(thread)
 
-			predeclared, err := getLibraryFuncs(in.KubeConfigPath, env)
-			if err != nil {
-				return err
+			var trace *log.APITrace
+			if in.TraceAPI {
+				f, err := os.Create(filepath.Join(env[sonobuoy.EnvKeySonobuoyResultsDir], log.APITraceFileName))
+				if err != nil {
+					sonobuoy.Done(thread)
+					return err
+				}
+				defer f.Close()
+				trace = log.NewAPITrace(f)
 			}
 
-			_, err = starlark.ExecFile(thread, in.Filename, nil, *predeclared)
+			predeclared, err := getLibraryFuncs(in, env, trace)
 			if err != nil {
-				if evalErr, ok := err.(*starlark.EvalError); ok {
-					sonobuoy.FailTest(thread, evalErr.Backtrace())
-					return errors.New(evalErr.Backtrace())
-				}
-				sonobuoy.FailTest(thread, err.Error())
+				sonobuoy.Done(thread)
 				return err
 			}
-			return nil
+
+			return runScript(thread, in.Filename, *predeclared)
 		},
 	}
 
 	root.Flags().StringVarP(&in.Filename, "file", "f", getDefaultScriptName(env), "The name of the script to run")
+	root.Flags().DurationVar(&in.Timeout, "timeout", 0, "(optional) maximum time the script may run for. The running test is failed if it is exceeded")
+	root.Flags().DurationVar(&in.TestTimeout, "test-timeout", 0, "(optional) maximum time each test may run for, from sonobuoy.startTest until it is passed or failed")
+	root.Flags().Uint64Var(&in.MaxSteps, "max-steps", 0, "(optional) maximum number of steps the script may take. Each call to a library function counts as one step")
+	root.Flags().StringArrayVar(&in.Contexts, "context", nil, "(optional) kubeconfig context to make available to the script via kube.context(name), as NAME or NAME=KUBECONFIG. May be repeated. Defaults to the --kubeconfig file")
+	root.Flags().StringArrayVar(&in.AllowPaths, "allow-path", nil, "(optional) file or directory which the fs module may access. May be repeated. By default no files may be accessed")
+	root.Flags().StringArrayVar(&in.AllowExec, "allow-exec", nil, "(optional) command which exec.run may run, as a name in the PATH or an absolute path. May be repeated. By default no commands may be run")
+	root.Flags().StringVar(&in.ValuesPath, "values", "", "(optional) YAML or JSON file of values for the params module. Defaults to "+params.DefaultValuesFile+" in the Sonobuoy config directory, if it exists")
+	root.Flags().BoolVar(&in.TraceAPI, "trace-api", false, "(optional) record every request made to the Kubernetes API (method, path, status and latency) to "+log.APITraceFileName+" in the results directory")
 	root.Flags().Var(&in.LogLevel, "level", "The Log level. One of {panic, fatal, error, warn, info, debug, trace}")
 	if home := homedir.HomeDir(); home != "" {
 		root.Flags().StringVar(&in.KubeConfigPath, "kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
@@ -98,13 +123,97 @@ func getRootCmd(env map[string]string) *cobra.Command {
 	}
 
 	root.AddCommand(NewCmdVersion())
+	root.AddCommand(NewCmdCheck(env))
 	return root
 }
 
-func getLibraryFuncs(kubeconfigPath string, currentEnv map[string]string) (*starlark.StringDict, error) {
+func getLibraryFuncs(in runInput, currentEnv map[string]string, trace *log.APITrace) (*starlark.StringDict, error) {
+	predeclared := getStaticLibraryFuncs()
+
+	// Script parameters via params.*, validated when the script declares them.
+	values, err := getValues(in.ValuesPath, currentEnv)
+	if err != nil {
+		return nil, err
+	}
+	predeclared["params"] = params.NewAPI(values)["params"]
+
+	// Host access via fs.* and exec.*, limited to what the user allowed.
+	allowedPaths, err := host.NewPathAllowlist(in.AllowPaths)
+	if err != nil {
+		return nil, err
+	}
+	predeclared["fs"] = host.NewFSAPI(allowedPaths)["fs"]
+	predeclared["exec"] = host.NewExecAPI(in.AllowExec)["exec"]
+
+	// Kubernetes API access via kube.*
+	def, err := newCluster(getClusterConfig(in.KubeConfigPath, currentEnv), trace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Other clusters via kube.context(name).
+	contexts := map[string]kube.Cluster{}
+	for _, spec := range in.Contexts {
+		name, kubeconfigPath := parseContextFlag(spec, in.KubeConfigPath)
+		c, err := getContextConfig(kubeconfigPath, name)
+		if err != nil {
+			return nil, err
+		}
+		if contexts[name], err = newCluster(c, trace); err != nil {
+			return nil, err
+		}
+	}
+
+	predeclared = guardBuiltins(predeclared)
+	predeclared["kube"] = kube.NewMultiCluster(def, contexts, true, false, false, ignoreDiffFields)["kube"]
+
+	return &predeclared, nil
+}
+
+// getValues loads the values for the params module from the given file or, if none is given, from the
+// default values file in the Sonobuoy config directory if there is one.
+func getValues(valuesPath string, currentEnv map[string]string) (map[string]interface{}, error) {
+	if len(valuesPath) > 0 {
+		return params.LoadValues(valuesPath)
+	}
+	return params.LoadValuesIfExists(filepath.Join(currentEnv[sonobuoy.EnvKeySonobuoyConfigDir], params.DefaultValuesFile))
+}
+
+// newCluster creates the clients used by the kube module to access the cluster. If trace is set,
+// every request made by the clients is recorded to it.
+func newCluster(c *rest.Config, trace *log.APITrace) (kube.Cluster, error) {
+	if trace != nil {
+		c.Wrap(trace.Wrap)
+	}
+	dC := discovery.NewDiscoveryClientForConfigOrDie(c)
+	t, err := rest.TransportFor(c)
+	if err != nil {
+		return kube.Cluster{}, err
+	}
+	dynC, err := dynamic.NewForConfig(c)
+	if err != nil {
+		return kube.Cluster{}, err
+	}
+	return kube.Cluster{Addr: c.Host, Discovery: dC, Dynamic: dynC, HTTPClient: &http.Client{Transport: t}, Config: c}, nil
+}
+
+// getStaticLibraryFuncs returns the predeclared values which do not require access to a cluster.
+func getStaticLibraryFuncs() starlark.StringDict {
 	predeclared := starlark.StringDict{
 		"sonobuoy": sonobuoy.API["sonobuoy"],
 		"env":      env.NewAPI()["env"],
+		"parallel": parallel.API["parallel"],
+		"check":    assert.CheckAPI["check"],
+		"time":     host.TimeAPI["time"],
+		"log":      log.API["log"],
+		"params":   params.NewAPI(nil)["params"],
+		"prom":     prom.API["prom"],
+		"tls":      certs.API["tls"],
+		"fs":       host.NewFSAPI(nil)["fs"],
+		"exec":     host.NewExecAPI(nil)["exec"],
+
+		// Reports missing kube.object fields as absent.
+		"hasattr": kube.HasAttr,
 
 		// ytt
 		"assert":  yttlibrary.AssertAPI["assert"],
@@ -125,22 +234,10 @@ func getLibraryFuncs(kubeconfigPath string, currentEnv map[string]string) (*star
 		"http": toStarlarkModule(nethttp.NewModule()),
 	}
 
-	// Custom overrides so that we can make custom error messages.
-	yttlibrary.AssertAPI["assert"].(*starlarkstruct.Module).Members["equals"] = starlark.NewBuiltin("assert.equals", core.ErrWrapper(assert.Equals))
-	yttlibrary.AssertAPI["assert"].(*starlarkstruct.Module).Members["fail"] = starlark.NewBuiltin("assert.fail", core.ErrWrapper(assert.Fail))
-
-	// Kubernetes API access via kube.*
-	c := getClusterConfig(kubeconfigPath, currentEnv)
-	dC := discovery.NewDiscoveryClientForConfigOrDie(c)
-	t, err := rest.TransportFor(c)
-	if err != nil {
-		return nil, err
-	}
-	dynC, err := dynamic.NewForConfig(c)
-	if err != nil {
-		return nil, err
+	// Custom overrides so that we can make custom error messages, plus our additional assertions.
+	for name, fn := range assert.Members() {
+		yttlibrary.AssertAPI["assert"].(*starlarkstruct.Module).Members[name] = fn
 	}
-	predeclared["kube"] = kube.New(c.Host, dC, dynC, &http.Client{Transport: t}, c, true, false, false, ignoreDiffFields)["kube"]
 
-	return &predeclared, nil
+	return predeclared
 }
@@ -5,7 +5,6 @@ go 1.17
 require (
 	github.com/cruise-automation/isopod v1.8.6
 	github.com/cruise-automation/rbacsync v1.0.0
-	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
 	github.com/google/go-cmp v0.5.6
 	github.com/heptio/ark v0.9.11
 	github.com/k14s/starlark-go v0.0.0-20200720175618-3a5c849cc368
@@ -36,6 +35,7 @@ require (
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/go-logr/logr v0.4.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/gofuzz v1.2.0 // indirect
 	github.com/googleapis/gnostic v0.5.5 // indirect
@@ -17,14 +17,15 @@ limitations under the License.
 package assert
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/orderedmap"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
 )
 
 const (
@@ -46,7 +47,11 @@ func Fail(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwa
 		return starlark.None, err
 	}
 
-	return starlark.None, fmt.Errorf("fail: %s", val)
+	shared.AddFailureDetails(thread, map[string]interface{}{
+		"assertion": f.Name(),
+		"message":   val,
+	})
+	return starlark.None, &assertionError{msg: "fail: " + val}
 }
 
 // Equals is a slightly modified copy of yttlibrary.assertLibrary.Equals so we can provide our own failure message.
@@ -80,12 +85,45 @@ func Equals(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, k
 		if len(args) == 3 {
 			errMsg = args.Index(2).String()
 		}
-		return starlark.None, errors.New(getFmtStringFromArgs(errMsg, expected, actual))
+		return starlark.None, failAssertion(thread, f, errMsg, operand{"expected", expected}, operand{"actual", actual}, map[string]interface{}{
+			"diff": diff(expected, actual),
+		})
 	}
 
 	return starlark.None, nil
 }
 
+// operand is a named value which an assertion was made about.
+type operand struct {
+	name  string
+	value starlark.Value
+}
+
+// failAssertion records the details of a failed assertion so they are reported with the current
+// test and returns the error which fails the script. The message is formatted via getFmtStringFromArgs
+// with the two operands.
+func failAssertion(thread *starlark.Thread, f *starlark.Builtin, msg string, op1, op2 operand, extra map[string]interface{}) error {
+	text := getFmtStringFromArgs(msg, op1.value, op2.value)
+	details := map[string]interface{}{
+		"assertion": f.Name(),
+		"message":   text,
+		op1.name:    op1.value.String(),
+		op2.name:    op2.value.String(),
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+	shared.AddFailureDetails(thread, details)
+	return &assertionError{msg: text}
+}
+
+// assertionError is returned when an assertion does not hold, as opposed to when it was called incorrectly.
+type assertionError struct {
+	msg string
+}
+
+func (e *assertionError) Error() string { return e.msg }
+
 // assertAsString is a copy from ytt unexported code to support the custom assert method.
 func assertAsString(value starlark.Value) (string, error) {
 	starlarkValue, err := core.NewStarlarkValue(value).AsGoValue()
@@ -126,15 +164,15 @@ func assertYamlEncode(goValue interface{}) (string, error) {
 // The keywords (e.g. $1) can be provided any number of times in any order (or not at all).
 func getFmtStringFromArgs(input string, v1, v2 starlark.Value) string {
 	// Only calc diff if necessary.
-	diff := ""
+	d := ""
 	if i3 := strings.Index(input, "$3"); i3 >= 0 {
-		diff = pretty.Compare(v1, v2)
+		d = diff(v1, v2)
 	}
 
 	rep := strings.NewReplacer(
 		"$1", fmt.Sprint(v1),
 		"$2", fmt.Sprint(v2),
-		"$3", fmt.Sprint(diff),
+		"$3", d,
 		"$4", v1.Type(),
 		"$5", v2.Type(),
 		`\n`, "\n",
@@ -144,3 +182,38 @@ func getFmtStringFromArgs(input string, v1, v2 starlark.Value) string {
 	// Run it through twice to resolve any newlines/tabs that get placed into the diff.
 	return rep.Replace(rep.Replace(input))
 }
+
+// diff compares the Go values of v1 and v2 rather than the starlark values, whose internal fields
+// would be compared instead of their contents.
+func diff(v1, v2 starlark.Value) string {
+	return pretty.Compare(diffValue(v1), diffValue(v2))
+}
+
+// diffValue converts the value to Go types which pretty can compare. Maps are converted from the
+// ordered maps used by ytt, and values which can't be converted are compared by their string form.
+func diffValue(v starlark.Value) interface{} {
+	goValue, err := core.NewStarlarkValue(v).AsGoValue()
+	if err != nil {
+		return v.String()
+	}
+	return plainGoValue(goValue)
+}
+
+func plainGoValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *orderedmap.Map:
+		out := make(map[string]interface{}, v.Len())
+		v.Iterate(func(k, item interface{}) {
+			out[fmt.Sprint(k)] = plainGoValue(item)
+		})
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = plainGoValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+const (
+	defaultNotEqualsMsg  = "Expected values to differ but both were:\n$1"
+	defaultContainsMsg   = "Expected $1 to contain $2"
+	defaultMatchesMsg    = "Expected $2 to match the regular expression $1"
+	defaultGreaterMsg    = "Expected $1 to be greater than $2"
+	defaultLessMsg       = "Expected $1 to be less than $2"
+	defaultApproxMsg     = "Expected $2 to be approximately $1"
+	defaultLenMsg        = "Expected $1 to have length $2"
+	defaultIsNoneMsg     = "Expected None but got $1 (type: $4)"
+	defaultSubsetOfMsg   = "Expected $1 to be a subset of $2"
+	defaultAllMsg        = "Expected all items to be true but these were not: $2"
+	defaultAnyMsg        = "Expected at least one item to be true: $1"
+	defaultEventuallyMsg = "Condition was not true within $1. Last result: $2"
+
+	defaultEventuallyInterval = time.Second
+)
+
+// assertions are the functions which sonolark adds to (or overrides in) ytt's assert module.
+var assertions = map[string]core.StarlarkFunc{
+	"equals":     Equals,
+	"fail":       Fail,
+	"not_equals": NotEquals,
+	"contains":   Contains,
+	"matches":    Matches,
+	"greater":    Greater,
+	"less":       Less,
+	"approx":     Approx,
+	"len":        Len,
+	"is_none":    IsNone,
+	"subset_of":  SubsetOf,
+	"all":        All,
+	"any":        Any,
+	"eventually": Eventually,
+}
+
+// Members returns the builtins which sonolark adds to (or overrides in) ytt's assert module.
+func Members() starlark.StringDict {
+	members := starlark.StringDict{}
+	for name, fn := range assertions {
+		members[name] = starlark.NewBuiltin("assert."+name, core.ErrWrapper(fn))
+	}
+	return members
+}
+
+// unpackWithMsg checks that there are n arguments, optionally followed by a custom failure
+// message, and returns the message to use.
+func unpackWithMsg(args starlark.Tuple, n int, defaultMsg string) (string, error) {
+	switch args.Len() {
+	case n:
+		return defaultMsg, nil
+	case n + 1:
+		msg, ok := starlark.AsString(args.Index(n))
+		if !ok {
+			return "", fmt.Errorf("expected message to be a string, but was %s", args.Index(n).Type())
+		}
+		return msg, nil
+	default:
+		return "", fmt.Errorf("expected %v or %v arguments", n, n+1)
+	}
+}
+
+// NotEquals asserts that the two values are not equal: assert.not_equals(v1, v2, [msg]).
+func NotEquals(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultNotEqualsMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	v1, v2 := args.Index(0), args.Index(1)
+	s1, err := assertAsString(v1)
+	if err != nil {
+		return starlark.None, err
+	}
+	s2, err := assertAsString(v2)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	if s1 == s2 {
+		return starlark.None, failAssertion(thread, f, msg, operand{"left", v1}, operand{"right", v2}, nil)
+	}
+	return starlark.None, nil
+}
+
+// Contains asserts that the container includes the item, using the semantics of the `in`
+// operator: assert.contains(container, item, [msg]).
+func Contains(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultContainsMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	container, item := args.Index(0), args.Index(1)
+	found, err := starlark.Binary(syntax.IN, item, container)
+	if err != nil {
+		return starlark.None, err
+	}
+	if !found.Truth() {
+		return starlark.None, failAssertion(thread, f, msg, operand{"container", container}, operand{"item", item}, nil)
+	}
+	return starlark.None, nil
+}
+
+// Matches asserts that the string matches the regular expression: assert.matches(regex, s, [msg]).
+func Matches(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultMatchesMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	pattern, err := core.NewStarlarkValue(args.Index(0)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+	s, err := core.NewStarlarkValue(args.Index(1)).AsString()
+	if err != nil {
+		return starlark.None, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	if !re.MatchString(s) {
+		return starlark.None, failAssertion(thread, f, msg, operand{"pattern", args.Index(0)}, operand{"value", args.Index(1)}, nil)
+	}
+	return starlark.None, nil
+}
+
+// Greater asserts that v1 > v2: assert.greater(v1, v2, [msg]).
+func Greater(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return compare(thread, f, args, syntax.GT, defaultGreaterMsg)
+}
+
+// Less asserts that v1 < v2: assert.less(v1, v2, [msg]).
+func Less(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return compare(thread, f, args, syntax.LT, defaultLessMsg)
+}
+
+func compare(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, op syntax.Token, defaultMsg string) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	v1, v2 := args.Index(0), args.Index(1)
+	ok, err := starlark.Compare(op, v1, v2)
+	if err != nil {
+		return starlark.None, err
+	}
+	if !ok {
+		return starlark.None, failAssertion(thread, f, msg, operand{"left", v1}, operand{"right", v2}, nil)
+	}
+	return starlark.None, nil
+}
+
+// Approx asserts that two numbers differ by no more than the tolerance:
+// assert.approx(expected, actual, tolerance, [msg]).
+func Approx(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var expected, actual, tolerance starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(f.Name(), args, kwargs, "expected", &expected, "actual", &actual, "tolerance", &tolerance, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if len(msg) == 0 {
+		msg = defaultApproxMsg
+	}
+
+	e, ok := starlark.AsFloat(expected)
+	if !ok {
+		return starlark.None, fmt.Errorf("expected a number but was %s", expected.Type())
+	}
+	a, ok := starlark.AsFloat(actual)
+	if !ok {
+		return starlark.None, fmt.Errorf("expected a number but was %s", actual.Type())
+	}
+	t, ok := starlark.AsFloat(tolerance)
+	if !ok || t < 0 {
+		return starlark.None, fmt.Errorf("expected tolerance to be a non-negative number but was %s", tolerance.String())
+	}
+
+	if math.Abs(e-a) > t {
+		return starlark.None, failAssertion(thread, f, msg, operand{"expected", expected}, operand{"actual", actual}, map[string]interface{}{
+			"tolerance":  t,
+			"difference": math.Abs(e - a),
+		})
+	}
+	return starlark.None, nil
+}
+
+// Len asserts that the value has the given length: assert.len(value, n, [msg]).
+func Len(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultLenMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	v := args.Index(0)
+	n, err := core.NewStarlarkValue(args.Index(1)).AsInt64()
+	if err != nil {
+		return starlark.None, err
+	}
+	l := starlark.Len(v)
+	if l < 0 {
+		return starlark.None, fmt.Errorf("value of type %s has no len", v.Type())
+	}
+
+	if int64(l) != n {
+		return starlark.None, failAssertion(thread, f, msg, operand{"value", v}, operand{"expected_length", args.Index(1)}, map[string]interface{}{
+			"length": l,
+		})
+	}
+	return starlark.None, nil
+}
+
+// IsNone asserts that the value is None: assert.is_none(value, [msg]).
+func IsNone(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 1, defaultIsNoneMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	v := args.Index(0)
+	if v != starlark.None {
+		return starlark.None, failAssertion(thread, f, msg, operand{"value", v}, operand{"expected", starlark.None}, nil)
+	}
+	return starlark.None, nil
+}
+
+// SubsetOf asserts that every item in the subset is in the superset: assert.subset_of(subset, superset, [msg]).
+// The items which are missing are reported in the failure details.
+func SubsetOf(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 2, defaultSubsetOfMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	subset, superset := args.Index(0), args.Index(1)
+	items, err := iterate(subset)
+	if err != nil {
+		return starlark.None, err
+	}
+	missing := []string{}
+	for _, item := range items {
+		found, err := starlark.Binary(syntax.IN, item, superset)
+		if err != nil {
+			return starlark.None, err
+		}
+		if !found.Truth() {
+			missing = append(missing, item.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return starlark.None, failAssertion(thread, f, msg, operand{"subset", subset}, operand{"superset", superset}, map[string]interface{}{
+			"missing": missing,
+		})
+	}
+	return starlark.None, nil
+}
+
+// All asserts that every item is true: assert.all(items, [msg]).
+func All(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 1, defaultAllMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	items, err := iterate(args.Index(0))
+	if err != nil {
+		return starlark.None, err
+	}
+	var falsy []starlark.Value
+	var indexes []int
+	for i, item := range items {
+		if !item.Truth() {
+			falsy = append(falsy, item)
+			indexes = append(indexes, i)
+		}
+	}
+
+	if len(falsy) > 0 {
+		return starlark.None, failAssertion(thread, f, msg, operand{"items", args.Index(0)}, operand{"false_items", starlark.NewList(falsy)}, map[string]interface{}{
+			"false_indexes": indexes,
+		})
+	}
+	return starlark.None, nil
+}
+
+// Any asserts that at least one item is true: assert.any(items, [msg]).
+func Any(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	msg, err := unpackWithMsg(args, 1, defaultAnyMsg)
+	if err != nil {
+		return starlark.None, err
+	}
+
+	items, err := iterate(args.Index(0))
+	if err != nil {
+		return starlark.None, err
+	}
+	for _, item := range items {
+		if item.Truth() {
+			return starlark.None, nil
+		}
+	}
+	return starlark.None, failAssertion(thread, f, msg, operand{"items", args.Index(0)}, operand{"expected", starlark.True}, nil)
+}
+
+// Eventually calls fn until it returns a true value without error, waiting interval between attempts,
+// and fails if that doesn't happen within the timeout: assert.eventually(fn, timeout, interval="1s", msg=).
+// Durations may be strings like "30s" or a number of seconds. Failures from the attempts which are retried
+// are not reported.
+func Eventually(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fn starlark.Callable
+	var timeoutArg, intervalArg starlark.Value
+	var msg string
+	if err := starlark.UnpackArgs(f.Name(), args, kwargs, "fn", &fn, "timeout", &timeoutArg, "interval?", &intervalArg, "msg?", &msg); err != nil {
+		return starlark.None, err
+	}
+	if len(msg) == 0 {
+		msg = defaultEventuallyMsg
+	}
+	timeout, err := toDuration(timeoutArg)
+	if err != nil {
+		return starlark.None, fmt.Errorf("invalid timeout: %v", err)
+	}
+	interval := defaultEventuallyInterval
+	if intervalArg != nil {
+		if interval, err = toDuration(intervalArg); err != nil {
+			return starlark.None, fmt.Errorf("invalid interval: %v", err)
+		}
+	}
+
+	ctx := shared.GetGoCtx(thread)
+	previousFailures, previousSoftFailures := shared.TakeFailureDetails(thread), shared.TakeSoftFailures(thread)
+	defer func() {
+		for _, d := range append(previousFailures, shared.TakeFailureDetails(thread)...) {
+			shared.AddFailureDetails(thread, d)
+		}
+		for _, m := range append(previousSoftFailures, shared.TakeSoftFailures(thread)...) {
+			shared.AddSoftFailure(thread, m)
+		}
+	}()
+
+	deadline := time.Now().Add(timeout)
+	var last starlark.Value = starlark.None
+	for {
+		v, err := starlark.Call(thread, fn, nil, nil)
+		// Failures from the attempts are summarized by the last result rather than reported. Failed soft
+		// assertions fail the attempt.
+		shared.TakeFailureDetails(thread)
+		if soft := shared.TakeSoftFailures(thread); len(soft) > 0 && err == nil {
+			err = errors.New(strings.Join(soft, "; "))
+		}
+		switch {
+		case err == nil && bool(v.Truth()):
+			return starlark.None, nil
+		case err != nil:
+			last = starlark.String(err.Error())
+		default:
+			last = v
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return starlark.None, fmt.Errorf("%v: %v", f.Name(), shared.CancelReason(ctx))
+		}
+	}
+
+	return starlark.None, failAssertion(thread, f, msg, operand{"timeout", starlark.String(timeout.String())}, operand{"last_result", last}, nil)
+}
+
+func iterate(v starlark.Value) ([]starlark.Value, error) {
+	iter := starlark.Iterate(v)
+	if iter == nil {
+		return nil, fmt.Errorf("expected an iterable but was %s", v.Type())
+	}
+	defer iter.Done()
+	var items []starlark.Value
+	var item starlark.Value
+	for iter.Next(&item) {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func toDuration(v starlark.Value) (time.Duration, error) {
+	if s, ok := starlark.AsString(v); ok {
+		return time.ParseDuration(s)
+	}
+	if f, ok := starlark.AsFloat(v); ok {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("expected a duration string or number of seconds but was %s", v.Type())
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assert
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary"
+	sono "github.com/vmware-tanzu/sonobuoy-plugins/plugin-helper"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+func execWithAssert(t *testing.T, src string) (*starlark.Thread, error) {
+	thread := &starlark.Thread{}
+	shared.SetGoCtx(thread, context.Background())
+	sonobuoy.StartSuite(thread, -1)
+
+	predeclared := starlark.StringDict{
+		"assert":   &starlarkstruct.Module{Name: "assert", Members: Members()},
+		"check":    CheckAPI["check"],
+		"sonobuoy": sonobuoy.API["sonobuoy"],
+		"struct":   yttlibrary.StructAPI["struct"],
+	}
+	_, err := starlark.ExecFile(thread, "test.star", src, predeclared)
+	return thread, err
+}
+
+func TestAssertions(t *testing.T) {
+	testcases := []struct {
+		desc      string
+		src       string
+		expectErr string
+	}{
+		{desc: "not_equals passes", src: `assert.not_equals(1, 2)`},
+		{desc: "not_equals fails", src: `assert.not_equals({"a": 1}, {"a": 1})`, expectErr: "Expected values to differ but both were:"},
+		{desc: "equals diffs lists by value", src: `assert.equals([1, 2], [1, 3], "$3")`, expectErr: "  1,\n- 2,\n+ 3,\n ]"},
+		{desc: "contains list", src: `assert.contains([1, 2], 2)`},
+		{desc: "contains substring", src: `assert.contains("foobar", "oba")`},
+		{desc: "contains fails with custom message", src: `assert.contains([1], 2, "no $2 in $1")`, expectErr: "no 2 in [1]"},
+		{desc: "matches passes", src: `assert.matches("^v1\\.[0-9]+$", "v1.23")`},
+		{desc: "matches fails", src: `assert.matches("^v2", "v1.23")`, expectErr: `Expected "v1.23" to match the regular expression "^v2"`},
+		{desc: "matches invalid regex", src: `assert.matches("(", "x")`, expectErr: "error parsing regexp"},
+		{desc: "greater passes", src: `assert.greater(3, 2)`},
+		{desc: "greater fails", src: `assert.greater(2, 2)`, expectErr: "Expected 2 to be greater than 2"},
+		{desc: "less passes", src: `assert.less("a", "b")`},
+		{desc: "less incomparable", src: `assert.less("a", 1)`, expectErr: "not implemented"},
+		{desc: "approx passes", src: `assert.approx(100, 105, 5)`},
+		{desc: "approx fails", src: `assert.approx(1, 3, tolerance=1)`, expectErr: "Expected 3 to be approximately 1"},
+		{desc: "approx negative tolerance", src: `assert.approx(1, 2, -1)`, expectErr: "non-negative"},
+		{desc: "len passes", src: `assert.len([1, 2, 3], 3)`},
+		{desc: "len fails", src: `assert.len({"a": 1}, 2)`, expectErr: `Expected {"a": 1} to have length 2`},
+		{desc: "len of value without length", src: `assert.len(1, 1)`, expectErr: "has no len"},
+		{desc: "is_none passes", src: `assert.is_none(None)`},
+		{desc: "is_none fails", src: `assert.is_none("x")`, expectErr: `Expected None but got "x" (type: string)`},
+		{desc: "subset_of passes", src: `assert.subset_of(["a"], ["a", "b"])`},
+		{desc: "subset_of fails", src: `assert.subset_of(["a", "c"], ["a", "b"])`, expectErr: `Expected ["a", "c"] to be a subset of ["a", "b"]`},
+		{desc: "all passes", src: `assert.all([1, True, "x"])`},
+		{desc: "all fails", src: `assert.all([1, 0, ""])`, expectErr: `these were not: [0, ""]`},
+		{desc: "any passes", src: `assert.any([0, 1])`},
+		{desc: "any fails", src: `assert.any([])`, expectErr: "Expected at least one item to be true"},
+		{desc: "wrong number of arguments", src: `assert.contains([1])`, expectErr: "expected 2 or 3 arguments"},
+		{desc: "non-string message", src: `assert.greater(2, 1, 3)`, expectErr: "expected message to be a string"},
+		{
+			desc: "eventually passes once the condition is true",
+			src: `
+calls = []
+def cond():
+  calls.append(1)
+  return len(calls) >= 3
+
+assert.eventually(cond, "1s", interval="10ms")
+`,
+		}, {
+			desc: "eventually retries errors",
+			src: `
+calls = []
+def cond():
+  calls.append(1)
+  assert.len(calls, 2)
+  return True
+
+assert.eventually(cond, 1, interval="10ms")
+`,
+		}, {
+			desc: "eventually fails after the timeout",
+			src: `
+def cond():
+  return False
+
+assert.eventually(cond, "50ms", interval="10ms")
+`,
+			expectErr: `Condition was not true within "50ms". Last result: False`,
+		}, {
+			desc:      "eventually invalid timeout",
+			src:       `assert.eventually(str, "soon")`,
+			expectErr: "invalid timeout",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := execWithAssert(t, tc.src)
+			switch {
+			case err != nil && len(tc.expectErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.expectErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && !strings.Contains(err.Error(), tc.expectErr):
+				t.Fatalf("Expected error %q but got %q", tc.expectErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestFailureDetails(t *testing.T) {
+	testcases := []struct {
+		desc          string
+		src           string
+		expectDetails []map[string]interface{}
+	}{
+		{
+			desc: "Failed assertion is recorded with the test",
+			src: `
+def run():
+  sonobuoy.startTest("subset")
+  assert.subset_of(["a", "c"], ["a", "b"])
+
+run()
+`,
+			expectDetails: []map[string]interface{}{{
+				"assertion": "assert.subset_of",
+				"message":   `Expected ["a", "c"] to be a subset of ["a", "b"]`,
+				"subset":    `["a", "c"]`,
+				"superset":  `["a", "b"]`,
+				"missing":   []string{`"c"`},
+			}},
+		}, {
+			desc: "Only the final attempt of eventually is recorded",
+			src: `
+def cond():
+  assert.greater(1, 2)
+
+def run():
+  sonobuoy.startTest("eventually")
+  assert.eventually(cond, "30ms", interval="10ms")
+
+run()
+`,
+			expectDetails: []map[string]interface{}{{
+				"assertion":   "assert.eventually",
+				"message":     `Condition was not true within "30ms". Last result: "assert.greater: Expected 1 to be greater than 2"`,
+				"timeout":     `"30ms"`,
+				"last_result": `"assert.greater: Expected 1 to be greater than 2"`,
+			}},
+		}, {
+			desc: "Dicts are diffed by value",
+			src: `
+sonobuoy.startTest("dicts")
+assert.equals({"a": 1, "b": [1, 2]}, {"a": 2, "b": [1, 2]})
+`,
+			expectDetails: []map[string]interface{}{{
+				"assertion": "assert.equals",
+				"message":   "Not equal:\n\n\t\t\t(expected type: dict)\n{\"a\": 1, \"b\": [1, 2]}\n\n(was type: dict)\n{\"a\": 2, \"b\": [1, 2]}",
+				"expected":  `{"a": 1, "b": [1, 2]}`,
+				"actual":    `{"a": 2, "b": [1, 2]}`,
+				"diff":      " {\n- a: 1,\n+ a: 2,\n  b: [\n   1,\n   2,\n  ],\n }",
+			}},
+		}, {
+			desc: "Structs are diffed by value",
+			src: `
+sonobuoy.startTest("structs")
+assert.equals(struct.encode({"a": [1, 2]}), struct.encode({"a": [1, 3]}))
+`,
+			expectDetails: []map[string]interface{}{{
+				"assertion": "assert.equals",
+				"message":   "Not equal:\n\n\t\t\t(expected type: struct)\nstruct(...)\n\n(was type: struct)\nstruct(...)",
+				"expected":  `struct(...)`,
+				"actual":    `struct(...)`,
+				"diff":      " {\n  a: [\n   1,\n-  2,\n+  3,\n  ],\n }",
+			}},
+		}, {
+			desc: "Passing tests have no details",
+			src: `
+sonobuoy.startTest("ok")
+assert.is_none(None)
+sonobuoy.passTest()
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			thread, err := execWithAssert(t, tc.src)
+			if err != nil {
+				sonobuoy.FailRunningTest(thread, err.Error())
+			}
+
+			w := shared.GetGoCtx(thread).Value(sonobuoy.WriterCtxKey).(*sono.SonobuoyResultsWriter)
+			if len(w.Data.Items) != 1 {
+				t.Fatalf("Expected 1 test but got %v", len(w.Data.Items))
+			}
+			got, _ := w.Data.Items[0].Details["failures"].([]map[string]interface{})
+			if diff := pretty.Compare(tc.expectDetails, got); diff != "" {
+				t.Errorf("Unexpected failure details:\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assert
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+var (
+	// CheckAPI provides soft versions of the assertions, e.g. check.equals, which record their failures
+	// against the current test instead of stopping the script. The test is failed with all of the
+	// messages once it is passed, failed or otherwise completed.
+	CheckAPI = starlark.StringDict{
+		"check": &starlarkstruct.Module{
+			Name:    "check",
+			Members: checkMembers(),
+		},
+	}
+)
+
+func checkMembers() starlark.StringDict {
+	members := starlark.StringDict{}
+	for name, fn := range assertions {
+		members[name] = starlark.NewBuiltin("check."+name, core.ErrWrapper(soft(fn)))
+	}
+	return members
+}
+
+// soft wraps the assertion so that a failure is recorded against the thread rather than returned. Errors
+// from calling the assertion incorrectly are still returned. Since failures are only reported with a test,
+// it is an error to call a check when no test is running rather than silently dropping the failure.
+func soft(fn core.StarlarkFunc) core.StarlarkFunc {
+	return func(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(sonobuoy.CurrentTest(thread)) == 0 {
+			return starlark.None, fmt.Errorf("no test is running, call sonobuoy.startTest first or use the assert module")
+		}
+		_, err := fn(thread, f, args, kwargs)
+		var assertErr *assertionError
+		if errors.As(err, &assertErr) {
+			shared.AddSoftFailure(thread, assertErr.Error())
+			return starlark.False, nil
+		}
+		if err != nil {
+			return starlark.None, err
+		}
+		return starlark.True, nil
+	}
+}
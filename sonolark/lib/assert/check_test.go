@@ -0,0 +1,133 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package assert
+
+import (
+	"strings"
+	"testing"
+
+	sono "github.com/vmware-tanzu/sonobuoy-plugins/plugin-helper"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+func TestCheck(t *testing.T) {
+	testcases := []struct {
+		desc          string
+		src           string
+		expectErr     string
+		expectStatus  string
+		expectMsg     string
+		expectDetails int
+	}{
+		{
+			desc: "All failures are reported when the test is passed",
+			src: `
+sonobuoy.startTest("fields")
+check.equals(1, 2)
+check.greater(3, 2)
+check.contains(["a"], "b", "missing $2")
+sonobuoy.passTest()
+`,
+			expectStatus:  "failed",
+			expectMsg:     "2 check(s) failed:\n- Not equal:",
+			expectDetails: 2,
+		}, {
+			desc: "Messages are appended when the test is failed",
+			src: `
+sonobuoy.startTest("fields")
+check.is_none(1)
+sonobuoy.failTest("also broken")
+`,
+			expectStatus:  "failed",
+			expectMsg:     "also broken\n1 check(s) failed:\n- Expected None but got 1 (type: int)",
+			expectDetails: 1,
+		}, {
+			desc: "Test passes if all checks pass",
+			src: `
+sonobuoy.startTest("fields")
+def run():
+  if not check.len([1], 1):
+    fail("expected check to return True")
+  sonobuoy.passTest()
+
+run()
+`,
+			expectStatus: "passed",
+		}, {
+			desc: "Checks are reported when a test is left running",
+			src: `
+sonobuoy.startTest("fields")
+check.fail("bad")
+`,
+			expectStatus:  "failed",
+			expectMsg:     "left running\n1 check(s) failed:\n- fail: bad",
+			expectDetails: 1,
+		}, {
+			desc: "Incorrect calls still stop the script",
+			src: `
+sonobuoy.startTest("fields")
+check.equals(1)
+`,
+			expectErr: "check.equals: expected 2 or 3 arguments",
+		}, {
+			desc:      "Checks outside of a test are an error",
+			src:       `check.equals(1, 2)`,
+			expectErr: "check.equals: no test is running",
+		}, {
+			desc: "Checks after a test completes are an error",
+			src: `
+sonobuoy.startTest("fields")
+sonobuoy.passTest()
+check.equals(1, 1)
+`,
+			expectErr: "check.equals: no test is running",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			thread, err := execWithAssert(t, tc.src)
+			switch {
+			case err != nil && len(tc.expectErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.expectErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && !strings.Contains(err.Error(), tc.expectErr):
+				t.Fatalf("Expected error %q but got %q", tc.expectErr, err.Error())
+			case err != nil:
+				return
+			}
+			sonobuoy.FailRunningTest(thread, "left running")
+
+			w := shared.GetGoCtx(thread).Value(sonobuoy.WriterCtxKey).(*sono.SonobuoyResultsWriter)
+			if len(w.Data.Items) != 1 {
+				t.Fatalf("Expected 1 test but got %v", len(w.Data.Items))
+			}
+			item := w.Data.Items[0]
+			if item.Status != tc.expectStatus {
+				t.Errorf("Expected status %q but got %q", tc.expectStatus, item.Status)
+			}
+			if msg, _ := item.Details["output"].(string); !strings.HasPrefix(msg, tc.expectMsg) {
+				t.Errorf("Expected message to start with %q but got %q", tc.expectMsg, msg)
+			}
+			if failures, _ := item.Details["failures"].([]map[string]interface{}); len(failures) != tc.expectDetails {
+				t.Errorf("Expected %v failure details but got %v", tc.expectDetails, len(failures))
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certs provides the tls module for inspecting X.509 certificates, e.g. those stored in
+// Secrets or served by webhooks and API endpoints.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
+)
+
+// Certificate wraps a parsed X.509 certificate. Its fields are exposed as attributes, with the
+// validity period as time.time values so that it can be compared with time.now().
+type Certificate struct {
+	cert *x509.Certificate
+}
+
+var (
+	_ starlark.HasAttrs   = &Certificate{}
+	_ starlark.Comparable = &Certificate{}
+)
+
+// String implements starlark.Value.String.
+func (c *Certificate) String() string {
+	return fmt.Sprintf("tls.certificate(subject = %q, issuer = %q, not_after = %v)", c.cert.Subject.String(), c.cert.Issuer.String(), host.Time(c.cert.NotAfter))
+}
+
+// Type implements starlark.Value.Type.
+func (c *Certificate) Type() string { return "tls.certificate" }
+
+// Freeze implements starlark.Value.Freeze. Certificates are immutable so this is a no-op.
+func (c *Certificate) Freeze() {}
+
+// Truth implements starlark.Value.Truth.
+func (c *Certificate) Truth() starlark.Bool { return starlark.True }
+
+// Hash implements starlark.Value.Hash.
+func (c *Certificate) Hash() (uint32, error) {
+	return starlark.String(c.cert.Raw).Hash()
+}
+
+// CompareSameType implements starlark.Comparable. Certificates are equal if their encodings are.
+func (c *Certificate) CompareSameType(op syntax.Token, y starlark.Value, _ int) (bool, error) {
+	equal := c.cert.Equal(y.(*Certificate).cert)
+	switch op {
+	case syntax.EQL:
+		return equal, nil
+	case syntax.NEQ:
+		return !equal, nil
+	}
+	return false, fmt.Errorf("unsupported comparison %v for %v", op, c.Type())
+}
+
+// Attr implements starlark.HasAttrs.
+func (c *Certificate) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "subject":
+		return starlark.String(c.cert.Subject.String()), nil
+	case "common_name":
+		return starlark.String(c.cert.Subject.CommonName), nil
+	case "issuer":
+		return starlark.String(c.cert.Issuer.String()), nil
+	case "serial":
+		return starlark.String(c.cert.SerialNumber.String()), nil
+	case "sans":
+		return stringList(sans(c.cert)), nil
+	case "dns_names":
+		return stringList(c.cert.DNSNames), nil
+	case "ip_addresses":
+		var ips []string
+		for _, ip := range c.cert.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		return stringList(ips), nil
+	case "not_before":
+		return host.Time(c.cert.NotBefore), nil
+	case "not_after":
+		return host.Time(c.cert.NotAfter), nil
+	case "key_type":
+		return starlark.String(keyType(c.cert.PublicKey)), nil
+	case "signature_algorithm":
+		return starlark.String(c.cert.SignatureAlgorithm.String()), nil
+	case "is_ca":
+		return starlark.Bool(c.cert.IsCA), nil
+	case "pem":
+		return starlark.String(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})), nil
+	}
+	return nil, nil
+}
+
+// AttrNames implements starlark.HasAttrs.
+func (c *Certificate) AttrNames() []string {
+	return []string{
+		"common_name", "dns_names", "ip_addresses", "is_ca", "issuer", "key_type", "not_after", "not_before",
+		"pem", "sans", "serial", "signature_algorithm", "subject",
+	}
+}
+
+// sans returns all of the subject alternative names of the certificate.
+func sans(cert *x509.Certificate) []string {
+	out := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		out = append(out, ip.String())
+	}
+	out = append(out, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		out = append(out, u.String())
+	}
+	return out
+}
+
+// keyType describes the public key, e.g. "RSA-2048" or "ECDSA-P256".
+func keyType(key interface{}) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return "unknown"
+}
+
+func stringList(values []string) *starlark.List {
+	out := make([]starlark.Value, 0, len(values))
+	for _, v := range values {
+		out = append(out, starlark.String(v))
+	}
+	return starlark.NewList(out)
+}
+
+// parsePEM returns the certificates in the PEM data, skipping other blocks such as private keys.
+// Since the data of Secrets is base64 encoded, data which does not look like PEM is decoded first.
+func parsePEM(data string) ([]*x509.Certificate, error) {
+	raw := []byte(data)
+	if !strings.Contains(data, "-----BEGIN") {
+		decoded, err := decodeBase64(data)
+		if err != nil {
+			return nil, fmt.Errorf("data is neither PEM nor base64 encoded PEM")
+		}
+		raw = decoded
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, raw = pem.Decode(raw)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %d: %v", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
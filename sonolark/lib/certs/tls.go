@@ -0,0 +1,210 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+const (
+	// DefaultDialTimeout is how long tls.dial waits for the handshake if no timeout is given.
+	DefaultDialTimeout = 10 * time.Second
+)
+
+var (
+	API = starlark.StringDict{
+		"tls": &starlarkstruct.Module{
+			Name: "tls",
+			Members: starlark.StringDict{
+				"parse_pem": starlark.NewBuiltin("tls.parse_pem", ParsePEM),
+				"dial":      starlark.NewBuiltin("tls.dial", Dial),
+				"verify":    starlark.NewBuiltin("tls.verify", Verify),
+			},
+		},
+	}
+)
+
+// ParsePEM returns a list of the certificates in PEM data, which may be base64 encoded as it is in
+// the data of a Secret.
+func ParsePEM(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var data string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "data", &data); err != nil {
+		return starlark.None, err
+	}
+	certs, err := parsePEM(data)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	return certificateList(certs), nil
+}
+
+// Dial connects to the address (host:port) and returns the chain of certificates presented by the
+// peer, along with the negotiated version and cipher suite. The chain is not verified so that invalid
+// certificates can be inspected; use tls.verify to check it.
+func Dial(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var addr, sni string
+	var timeoutVal starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "addr", &addr, "sni?", &sni, "timeout?", &timeoutVal); err != nil {
+		return starlark.None, err
+	}
+	timeout := DefaultDialTimeout
+	if timeoutVal != starlark.None {
+		var err error
+		if timeout, err = host.ToDuration(timeoutVal); err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid timeout: %v", b.Name(), err)
+		}
+	}
+	if len(sni) == 0 {
+		h, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+		}
+		sni = h
+	}
+
+	ctx, cancel := context.WithTimeout(shared.GetGoCtx(thread), timeout)
+	defer cancel()
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: sni, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: failed to connect to %v: %v", b.Name(), addr, err)
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"chain":        certificateList(state.PeerCertificates),
+		"version":      starlark.String(tlsVersion(state.Version)),
+		"cipher_suite": starlark.String(tls.CipherSuiteName(state.CipherSuite)),
+		"server_name":  starlark.String(sni),
+	}), nil
+}
+
+// Verify checks that the chain (leaf first, followed by any intermediates) is signed by one of the
+// roots, or by the system roots if none are given. If a dns_name is given the leaf must be valid for
+// it. The chain is checked at the given time, which defaults to now. It returns a struct with `ok'
+// and the reason verification failed as `error'.
+func Verify(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var chainVal, rootsVal starlark.Value = nil, starlark.None
+	var dnsName string
+	var at starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "chain", &chainVal, "roots?", &rootsVal, "dns_name?", &dnsName, "at?", &at); err != nil {
+		return starlark.None, err
+	}
+
+	chain, err := toCertificates(chainVal)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: invalid `chain' arg: %v", b.Name(), err)
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       dnsName,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, c := range chain[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if rootsVal != starlark.None {
+		roots, err := toCertificates(rootsVal)
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid `roots' arg: %v", b.Name(), err)
+		}
+		opts.Roots = x509.NewCertPool()
+		for _, c := range roots {
+			opts.Roots.AddCert(c)
+		}
+	}
+	switch t := at.(type) {
+	case starlark.NoneType:
+	case host.Time:
+		opts.CurrentTime = time.Time(t)
+	default:
+		return starlark.None, fmt.Errorf("%v: expected a time.time for `at' arg, got: %s", b.Name(), at.Type())
+	}
+
+	result := starlark.StringDict{"ok": starlark.True, "error": starlark.String("")}
+	if _, err := chain[0].Verify(opts); err != nil {
+		result["ok"], result["error"] = starlark.False, starlark.String(err.Error())
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, result), nil
+}
+
+// toCertificates converts PEM data, a tls.certificate or a list of them to certificates.
+func toCertificates(v starlark.Value) ([]*x509.Certificate, error) {
+	switch t := v.(type) {
+	case starlark.String:
+		return parsePEM(string(t))
+	case *Certificate:
+		return []*x509.Certificate{t.cert}, nil
+	case *starlark.List:
+		var out []*x509.Certificate
+		for i := 0; i < t.Len(); i++ {
+			certs, err := toCertificates(t.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			out = append(out, certs...)
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("no certificates given")
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected PEM data, a tls.certificate or a list of them, got: %s", v.Type())
+}
+
+func certificateList(certs []*x509.Certificate) *starlark.List {
+	out := make([]starlark.Value, 0, len(certs))
+	for _, c := range certs {
+		out = append(out, &Certificate{cert: c})
+	}
+	return starlark.NewList(out)
+}
+
+func decodeBase64(data string) ([]byte, error) {
+	data = strings.TrimSpace(data)
+	if b, err := base64.StdEncoding.DecodeString(data); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(data)
+}
+
+func tlsVersion(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+const (
+	// DefaultExecTimeout is how long exec.run waits for a command if no timeout is given.
+	DefaultExecTimeout = time.Minute
+)
+
+type execModule struct {
+	allowed CommandAllowlist
+}
+
+// NewExecAPI returns the "exec" module which may only run the allowed commands.
+func NewExecAPI(allowed CommandAllowlist) starlark.StringDict {
+	m := &execModule{allowed: allowed}
+	return starlark.StringDict{
+		"exec": &starlarkstruct.Module{
+			Name: "exec",
+			Members: starlark.StringDict{
+				"run": starlark.NewBuiltin("exec.run", m.run),
+			},
+		},
+	}
+}
+
+// run runs the command, given as a list of arguments or a string which is split on whitespace, and
+// returns its stdout, stderr and exit code. The command is not run via a shell. A command exiting
+// with a non-zero code is not an error but one which can't be started or times out is.
+func (m *execModule) run(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var cmd starlark.Value
+	var timeoutVal starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "cmd", &cmd, "timeout?", &timeoutVal); err != nil {
+		return starlark.None, err
+	}
+
+	argv, err := commandArgs(cmd)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	if err := m.allowed.Check(argv[0]); err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	timeout := DefaultExecTimeout
+	if timeoutVal != starlark.None {
+		if timeout, err = ToDuration(timeoutVal); err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid timeout: %v", b.Name(), err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(shared.GetGoCtx(thread), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Stdout, c.Stderr = &stdout, &stderr
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return starlark.None, fmt.Errorf("%v: %q did not complete within %v", b.Name(), strings.Join(argv, " "), timeout)
+	case errors.As(err, &exitErr):
+	case err != nil:
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"stdout":    starlark.String(stdout.String()),
+		"stderr":    starlark.String(stderr.String()),
+		"exit_code": starlark.MakeInt(c.ProcessState.ExitCode()),
+	}), nil
+}
+
+func commandArgs(cmd starlark.Value) ([]string, error) {
+	var argv []string
+	switch t := cmd.(type) {
+	case starlark.String:
+		argv = strings.Fields(string(t))
+	case *starlark.List:
+		for i := 0; i < t.Len(); i++ {
+			s, ok := starlark.AsString(t.Index(i))
+			if !ok {
+				return nil, fmt.Errorf("expected string values for `cmd' arg, got: %s", t.Index(i).Type())
+			}
+			argv = append(argv, s)
+		}
+	default:
+		return nil, fmt.Errorf("expected a string or list for `cmd' arg, got: %s", cmd.Type())
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("cmd must not be empty")
+	}
+	return argv, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+)
+
+type fsModule struct {
+	allowed PathAllowlist
+}
+
+// NewFSAPI returns the "fs" module which may only access the allowed paths.
+func NewFSAPI(allowed PathAllowlist) starlark.StringDict {
+	m := &fsModule{allowed: allowed}
+	return starlark.StringDict{
+		"fs": &starlarkstruct.Module{
+			Name: "fs",
+			Members: starlark.StringDict{
+				"read": starlark.NewBuiltin("fs.read", m.read),
+				"glob": starlark.NewBuiltin("fs.glob", m.glob),
+				"stat": starlark.NewBuiltin("fs.stat", m.stat),
+			},
+		},
+	}
+}
+
+// read returns the contents of the file as a string.
+func (m *fsModule) read(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path); err != nil {
+		return starlark.None, err
+	}
+	resolved, err := m.allowed.Check(path)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	data, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	return starlark.String(data), nil
+}
+
+// glob returns the sorted paths matching the pattern, using the syntax of filepath.Match. Matches
+// which are not allowed are left out.
+func (m *fsModule) glob(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern); err != nil {
+		return starlark.None, err
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	sort.Strings(matches)
+
+	out := []starlark.Value{}
+	for _, match := range matches {
+		if _, err := m.allowed.Check(match); err == nil {
+			out = append(out, starlark.String(match))
+		}
+	}
+	return starlark.NewList(out), nil
+}
+
+// stat returns information about the file, following symlinks, or None if it does not exist.
+func (m *fsModule) stat(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path); err != nil {
+		return starlark.None, err
+	}
+	resolved, err := m.allowed.Check(path)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	info, err := os.Stat(resolved)
+	if os.IsNotExist(err) {
+		return starlark.None, nil
+	} else if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"path":     starlark.String(path),
+		"name":     starlark.String(info.Name()),
+		"size":     starlark.MakeInt64(info.Size()),
+		"mode":     starlark.String(info.Mode().String()),
+		"perm":     starlark.MakeInt(int(info.Mode().Perm())),
+		"is_dir":   starlark.Bool(info.IsDir()),
+		"mod_time": Time(info.ModTime()),
+	}), nil
+}
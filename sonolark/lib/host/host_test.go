@@ -0,0 +1,164 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+func eval(t *testing.T, expr string, predeclared starlark.StringDict) (starlark.Value, error) {
+	t.Helper()
+	thread := &starlark.Thread{Name: t.Name()}
+	shared.SetGoCtx(thread, context.Background())
+	return starlark.Eval(thread, "test", expr, predeclared)
+}
+
+func checkResult(t *testing.T, v starlark.Value, err error, want, wantErr string) {
+	t.Helper()
+	switch {
+	case err != nil && len(wantErr) == 0:
+		t.Fatalf("Unexpected error: %v", err)
+	case err == nil && len(wantErr) > 0:
+		t.Fatalf("Expected error %q but got nil", wantErr)
+	case err != nil && !strings.Contains(err.Error(), wantErr):
+		t.Fatalf("Expected error %q but got %q", wantErr, err.Error())
+	case err != nil:
+		return
+	}
+	if v.String() != want {
+		t.Errorf("Expected %v but got %v", want, v.String())
+	}
+}
+
+func TestFS(t *testing.T) {
+	dir := t.TempDir()
+	allowedDir := filepath.Join(dir, "allowed")
+	if err := os.MkdirAll(filepath.Join(allowedDir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range map[string]string{
+		"allowed/a.conf":     "a=1",
+		"allowed/b.conf":     "b=2",
+		"allowed/sub/c.conf": "c=3",
+		"secret":             "hunter2",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret"), filepath.Join(allowedDir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed, err := NewPathAllowlist([]string{allowedDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	predeclared := starlark.StringDict{
+		"fs":   NewFSAPI(allowed)["fs"],
+		"none": NewFSAPI(nil)["fs"],
+		"dir":  starlark.String(dir),
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Read allowed file", expr: `fs.read(dir + "/allowed/a.conf")`, want: `"a=1"`},
+		{desc: "Read nested file", expr: `fs.read(dir + "/allowed/sub/c.conf")`, want: `"c=3"`},
+		{desc: "Read file outside allowlist", expr: `fs.read(dir + "/secret")`, wantErr: "is not allowed, use --allow-path"},
+		{desc: "Relative paths are resolved", expr: `fs.read(dir + "/allowed/../secret")`, wantErr: "is not allowed"},
+		{desc: "Symlinks are resolved", expr: `fs.read(dir + "/allowed/escape")`, wantErr: "is not allowed"},
+		{desc: "Nothing allowed by default", expr: `none.read(dir + "/allowed/a.conf")`, wantErr: "is not allowed"},
+		{desc: "Read missing file", expr: `fs.read(dir + "/allowed/missing")`, wantErr: "no such file"},
+		{desc: "Glob", expr: `[p.split("/")[-1] for p in fs.glob(dir + "/allowed/*.conf")]`, want: `["a.conf", "b.conf"]`},
+		{desc: "Glob leaves out disallowed matches", expr: `[p.split("/")[-1] for p in fs.glob(dir + "/*")]`, want: `["allowed"]`},
+		{desc: "Invalid glob", expr: `fs.glob("[")`, wantErr: "syntax error in pattern"},
+		{desc: "Stat file", expr: `(fs.stat(dir + "/allowed/a.conf").size, fs.stat(dir + "/allowed/a.conf").mode, fs.stat(dir + "/allowed/a.conf").is_dir)`, want: `(3, "-rw-------", False)`},
+		{desc: "Stat dir", expr: `fs.stat(dir + "/allowed/sub").is_dir`, want: "True"},
+		{desc: "Stat missing file", expr: `fs.stat(dir + "/allowed/missing")`, want: "None"},
+		{desc: "Stat file outside allowlist", expr: `fs.stat(dir + "/secret")`, wantErr: "is not allowed"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := eval(t, tc.expr, predeclared)
+			checkResult(t, v, err, tc.want, tc.wantErr)
+		})
+	}
+}
+
+func TestTime(t *testing.T) {
+	predeclared := starlark.StringDict{"time": TimeAPI["time"]}
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Parse RFC 3339", expr: `time.parse("2022-03-04T05:06:07Z")`, want: "2022-03-04T05:06:07Z"},
+		{desc: "Parse with layout", expr: `time.parse("Mar  4 05:06:07 2022 GMT", "Jan _2 15:04:05 2006 MST").day`, want: "4"},
+		{desc: "Invalid time", expr: `time.parse("yesterday")`, wantErr: "time.parse: parsing time"},
+		{desc: "Compare times", expr: `time.parse("2022-03-04T05:06:07Z") < time.now()`, want: "True"},
+		{desc: "Subtract times", expr: `time.parse("2022-03-05T05:06:07Z") - time.parse("2022-03-04T05:06:07Z")`, want: "24h0m0s"},
+		{desc: "Add duration to time", expr: `time.parse("2022-03-04T05:06:07Z") + time.duration("1h")`, want: "2022-03-04T06:06:07Z"},
+		{desc: "Subtract duration from time", expr: `(time.parse("2022-03-04T05:06:07Z") - time.duration("1h")).hour`, want: "4"},
+		{desc: "Format", expr: `time.parse("2022-03-04T05:06:07Z").format("2006-01-02")`, want: `"2022-03-04"`},
+		{desc: "Duration from seconds", expr: `time.duration(90)`, want: "1m30s"},
+		{desc: "Duration arithmetic", expr: `(time.duration("1h") - time.duration("30m")) * 3`, want: "1h30m0s"},
+		{desc: "Compare durations", expr: `time.duration("90s") == time.duration("1m30s")`, want: "True"},
+		{desc: "Duration units", expr: `(time.duration("36h").hours, time.duration("90s").minutes, time.duration("1500ms").milliseconds)`, want: "(36, 1, 1500)"},
+		{desc: "Invalid duration", expr: `time.duration("soon")`, wantErr: "time.duration: time: invalid duration"},
+		{desc: "Cannot add times", expr: `time.now() + time.now()`, wantErr: "unknown binary op"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := eval(t, tc.expr, predeclared)
+			checkResult(t, v, err, tc.want, tc.wantErr)
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	predeclared := starlark.StringDict{"exec": NewExecAPI(CommandAllowlist{"echo", "sh", "sleep"})["exec"]}
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Run string", expr: `exec.run("echo hello  world").stdout`, want: `"hello world\n"`},
+		{desc: "Run list", expr: `exec.run(["sh", "-c", "echo oops >&2; exit 3"])`, want: `struct(exit_code = 3, stderr = "oops\n", stdout = "")`},
+		{desc: "Command not allowed", expr: `exec.run("cat /etc/passwd")`, wantErr: `exec.run: running "cat" is not allowed, use --allow-exec`},
+		{desc: "Path must match allowlist", expr: `exec.run("/bin/echo hi")`, wantErr: "is not allowed"},
+		{desc: "Empty command", expr: `exec.run([])`, wantErr: "cmd must not be empty"},
+		{desc: "Timeout", expr: `exec.run("sleep 5", timeout="100ms")`, wantErr: `"sleep 5" did not complete within 100ms`},
+		{desc: "Invalid timeout", expr: `exec.run(["sh", "-c", "exit 0"], timeout="bad")`, wantErr: "invalid timeout"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := eval(t, tc.expr, predeclared)
+			checkResult(t, v, err, tc.want, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package host provides the fs, time and exec modules which let scripts inspect the node they run on,
+// e.g. when run as a DaemonSet plugin. Access to files and commands is limited to those allowed by
+// the user.
+package host
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PathAllowlist holds the files and directories which the fs module may access. Directories allow
+// access to everything beneath them.
+type PathAllowlist []string
+
+// NewPathAllowlist returns an allowlist of the paths, which are resolved to absolute paths without
+// symlinks so that links can't be used to escape them.
+func NewPathAllowlist(paths []string) (PathAllowlist, error) {
+	var out PathAllowlist
+	for _, p := range paths {
+		resolved, err := resolvePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed path %q: %v", p, err)
+		}
+		out = append(out, resolved)
+	}
+	return out, nil
+}
+
+// Check returns the resolved path if it is allowed, otherwise an error.
+func (a PathAllowlist) Check(path string) (string, error) {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	for _, allowed := range a {
+		if resolved == allowed || strings.HasPrefix(resolved, strings.TrimSuffix(allowed, string(filepath.Separator))+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("access to %q is not allowed, use --allow-path to allow it", path)
+}
+
+// resolvePath returns the absolute path with any symlinks evaluated. Paths which do not exist are
+// only cleaned since there is nothing to follow.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if os.IsNotExist(err) {
+		return abs, nil
+	}
+	return resolved, err
+}
+
+// CommandAllowlist holds the commands which the exec module may run, either as a name looked up
+// in the PATH or an absolute path.
+type CommandAllowlist []string
+
+// Check returns an error if the command is not allowed. Commands must be given exactly as they were
+// allowed so that a name can't be used to run a different binary.
+func (a CommandAllowlist) Check(command string) error {
+	for _, allowed := range a {
+		if command == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("running %q is not allowed, use --allow-exec to allow it", command)
+}
@@ -0,0 +1,307 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+var (
+	// TimeAPI is the "time" module. It does not access the host so is always available.
+	TimeAPI = starlark.StringDict{
+		"time": &starlarkstruct.Module{
+			Name: "time",
+			Members: starlark.StringDict{
+				"now":      starlark.NewBuiltin("time.now", Now),
+				"parse":    starlark.NewBuiltin("time.parse", Parse),
+				"duration": starlark.NewBuiltin("time.duration", ParseDuration),
+			},
+		},
+	}
+)
+
+// Now returns the current time.
+func Now(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return starlark.None, err
+	}
+	return Time(time.Now()), nil
+}
+
+// Parse parses a time in the given Go layout, which defaults to RFC 3339 (e.g. "2022-03-04T05:06:07Z").
+func Parse(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value string
+	format := time.RFC3339
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "value", &value, "format?", &format); err != nil {
+		return starlark.None, err
+	}
+	t, err := time.Parse(format, value)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	return Time(t), nil
+}
+
+// ParseDuration returns a duration from a string like "1h30m" or a number of seconds.
+func ParseDuration(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "value", &value); err != nil {
+		return starlark.None, err
+	}
+	d, err := ToDuration(value)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	return Duration(d), nil
+}
+
+// ToDuration converts a duration string (e.g. "30s"), a number of seconds or a time.duration to a
+// time.Duration.
+func ToDuration(v starlark.Value) (time.Duration, error) {
+	switch t := v.(type) {
+	case Duration:
+		return time.Duration(t), nil
+	case starlark.String:
+		return time.ParseDuration(string(t))
+	case starlark.Int:
+		s, ok := t.Int64()
+		if !ok {
+			return 0, fmt.Errorf("duration out of range: %v", t)
+		}
+		return time.Duration(s) * time.Second, nil
+	case starlark.Float:
+		return time.Duration(float64(t) * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("expected a duration string or number of seconds, got: %s", v.Type())
+}
+
+// Time is a point in time. Times can be compared, subtracted to give a Duration and moved by adding
+// or subtracting a Duration.
+type Time time.Time
+
+var (
+	_ starlark.Comparable = Time{}
+	_ starlark.HasBinary  = Time{}
+	_ starlark.HasAttrs   = Time{}
+)
+
+// String implements starlark.Value.String.
+func (t Time) String() string { return time.Time(t).Format(time.RFC3339Nano) }
+
+// Type implements starlark.Value.Type.
+func (t Time) Type() string { return "time.time" }
+
+// Freeze implements starlark.Value.Freeze. Times are immutable so this is a no-op.
+func (t Time) Freeze() {}
+
+// Truth implements starlark.Value.Truth.
+// Returns true unless it is the zero time.
+func (t Time) Truth() starlark.Bool { return starlark.Bool(!time.Time(t).IsZero()) }
+
+// Hash implements starlark.Value.Hash.
+func (t Time) Hash() (uint32, error) {
+	n := time.Time(t).UnixNano()
+	return uint32(n ^ (n >> 32)), nil
+}
+
+// CompareSameType implements starlark.Comparable.
+func (t Time) CompareSameType(op syntax.Token, y starlark.Value, _ int) (bool, error) {
+	a, b := time.Time(t), time.Time(y.(Time))
+	cmp := 0
+	switch {
+	case a.Before(b):
+		cmp = -1
+	case a.After(b):
+		cmp = 1
+	}
+	return compare(op, cmp, t.Type())
+}
+
+// Binary implements starlark.HasBinary.
+func (t Time) Binary(op syntax.Token, y starlark.Value, side starlark.Side) (starlark.Value, error) {
+	switch y := y.(type) {
+	case Duration:
+		switch {
+		case op == syntax.PLUS:
+			return Time(time.Time(t).Add(time.Duration(y))), nil
+		case op == syntax.MINUS && side == starlark.Left:
+			return Time(time.Time(t).Add(-time.Duration(y))), nil
+		}
+	case Time:
+		if op == syntax.MINUS {
+			if side == starlark.Left {
+				return Duration(time.Time(t).Sub(time.Time(y))), nil
+			}
+			return Duration(time.Time(y).Sub(time.Time(t))), nil
+		}
+	}
+	return nil, nil
+}
+
+// Attr implements starlark.HasAttrs.
+func (t Time) Attr(name string) (starlark.Value, error) {
+	tt := time.Time(t)
+	switch name {
+	case "year":
+		return starlark.MakeInt(tt.Year()), nil
+	case "month":
+		return starlark.MakeInt(int(tt.Month())), nil
+	case "day":
+		return starlark.MakeInt(tt.Day()), nil
+	case "hour":
+		return starlark.MakeInt(tt.Hour()), nil
+	case "minute":
+		return starlark.MakeInt(tt.Minute()), nil
+	case "second":
+		return starlark.MakeInt(tt.Second()), nil
+	case "unix":
+		return starlark.MakeInt64(tt.Unix()), nil
+	case "format":
+		return shared.GuardBuiltin(starlark.NewBuiltin("format", t.format)), nil
+	}
+	return nil, nil
+}
+
+// AttrNames implements starlark.HasAttrs.
+func (t Time) AttrNames() []string {
+	return []string{"day", "format", "hour", "minute", "month", "second", "unix", "year"}
+}
+
+func (t Time) format(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var layout string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "layout", &layout); err != nil {
+		return starlark.None, err
+	}
+	return starlark.String(time.Time(t).Format(layout)), nil
+}
+
+// AsGoValue allows times to be used with the ytt libraries (e.g. assert.equals and json.encode).
+func (t Time) AsGoValue() (interface{}, error) {
+	return t.String(), nil
+}
+
+// Duration is a length of time. Durations can be compared, added and subtracted and multiplied by
+// an integer.
+type Duration time.Duration
+
+var (
+	_ starlark.Comparable = Duration(0)
+	_ starlark.HasBinary  = Duration(0)
+	_ starlark.HasAttrs   = Duration(0)
+)
+
+// String implements starlark.Value.String.
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// Type implements starlark.Value.Type.
+func (d Duration) Type() string { return "time.duration" }
+
+// Freeze implements starlark.Value.Freeze. Durations are immutable so this is a no-op.
+func (d Duration) Freeze() {}
+
+// Truth implements starlark.Value.Truth.
+// Returns true if the duration is non-zero.
+func (d Duration) Truth() starlark.Bool { return d != 0 }
+
+// Hash implements starlark.Value.Hash.
+func (d Duration) Hash() (uint32, error) {
+	return uint32(d ^ (d >> 32)), nil
+}
+
+// CompareSameType implements starlark.Comparable.
+func (d Duration) CompareSameType(op syntax.Token, y starlark.Value, _ int) (bool, error) {
+	other := y.(Duration)
+	cmp := 0
+	switch {
+	case d < other:
+		cmp = -1
+	case d > other:
+		cmp = 1
+	}
+	return compare(op, cmp, d.Type())
+}
+
+// Binary implements starlark.HasBinary.
+func (d Duration) Binary(op syntax.Token, y starlark.Value, side starlark.Side) (starlark.Value, error) {
+	switch y := y.(type) {
+	case Duration:
+		switch {
+		case op == syntax.PLUS:
+			return d + y, nil
+		case op == syntax.MINUS && side == starlark.Left:
+			return d - y, nil
+		case op == syntax.MINUS:
+			return y - d, nil
+		}
+	case starlark.Int:
+		n, ok := y.Int64()
+		if ok && op == syntax.STAR {
+			return d * Duration(n), nil
+		}
+	}
+	return nil, nil
+}
+
+// Attr implements starlark.HasAttrs. Each unit gives the whole number of that unit in the duration.
+func (d Duration) Attr(name string) (starlark.Value, error) {
+	td := time.Duration(d)
+	switch name {
+	case "hours":
+		return starlark.MakeInt64(int64(td / time.Hour)), nil
+	case "minutes":
+		return starlark.MakeInt64(int64(td / time.Minute)), nil
+	case "seconds":
+		return starlark.MakeInt64(int64(td / time.Second)), nil
+	case "milliseconds":
+		return starlark.MakeInt64(td.Milliseconds()), nil
+	}
+	return nil, nil
+}
+
+// AttrNames implements starlark.HasAttrs.
+func (d Duration) AttrNames() []string {
+	return []string{"hours", "milliseconds", "minutes", "seconds"}
+}
+
+// AsGoValue allows durations to be used with the ytt libraries (e.g. assert.equals and json.encode).
+func (d Duration) AsGoValue() (interface{}, error) {
+	return d.String(), nil
+}
+
+func compare(op syntax.Token, cmp int, typ string) (bool, error) {
+	switch op {
+	case syntax.EQL:
+		return cmp == 0, nil
+	case syntax.NEQ:
+		return cmp != 0, nil
+	case syntax.LT:
+		return cmp < 0, nil
+	case syntax.LE:
+		return cmp <= 0, nil
+	case syntax.GT:
+		return cmp > 0, nil
+	case syntax.GE:
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unsupported comparison %v for %v", op, typ)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cruise-automation/isopod/pkg/addon"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	selfSubjectAccessReviewPath = "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"
+)
+
+// kubeAsUserFn is an entry point for the `kube.as_user` built-in. It returns a copy of the kube module
+// whose requests impersonate the given user and groups, e.g. kube.as_user("system:serviceaccount:ns:name").
+// Discovery still uses the original credentials since the user may not be allowed to perform it.
+func (m *kubePackage) kubeAsUserFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var user string
+	groups := &starlark.List{}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "user", &user, "groups?", &groups); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+	if len(user) == 0 {
+		return nil, fmt.Errorf("<%v>: user must not be empty", b.Name())
+	}
+
+	impersonate := rest.ImpersonationConfig{UserName: user}
+	for i := 0; i < groups.Len(); i++ {
+		g, ok := starlark.AsString(groups.Index(i))
+		if !ok {
+			return nil, fmt.Errorf("<%v>: expected string values for `groups' arg, got: %s", b.Name(), groups.Index(i).Type())
+		}
+		impersonate.Groups = append(impersonate.Groups, g)
+	}
+
+	pkg, err := m.impersonate(impersonate)
+	if err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+	return pkg.module(), nil
+}
+
+// impersonate returns a copy of the package whose clients impersonate the given user.
+func (m *kubePackage) impersonate(impersonate rest.ImpersonationConfig) (*kubePackage, error) {
+	if m.config == nil {
+		return nil, fmt.Errorf("no client configuration available to impersonate %q", impersonate.UserName)
+	}
+	config := rest.CopyConfig(m.config)
+	config.Impersonate = impersonate
+
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %v", err)
+	}
+	dynC, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+
+	pkg := *m
+	pkg.config = config
+	pkg.httpClient = &http.Client{Transport: transport}
+	pkg.dynClient = dynC
+	return &pkg, nil
+}
+
+// kubeCanIFn is an entry point for the `kube.can_i` built-in which returns whether the current user
+// (which may be impersonated via kube.as_user) may perform the verb on the resource, like
+// `kubectl auth can-i`. It is answered by the API server via a SelfSubjectAccessReview.
+func (m *kubePackage) kubeCanIFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var verb, resource, namespace, name, apiGroup string
+	unpacked := []interface{}{
+		"verb", &verb,
+		"resource", &resource,
+		"namespace?", &namespace,
+		"name?", &name,
+		apiGroupKW + "?", &apiGroup,
+	}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+
+	attrs := &authorizationv1.ResourceAttributes{
+		Verb:      verb,
+		Namespace: namespace,
+		Name:      name,
+	}
+	resource, attrs.Subresource = splitSubresource(resource)
+	if resource == "*" {
+		attrs.Resource, attrs.Group = resource, apiGroup
+	} else {
+		r, err := newResource(m.dClient, name, namespace, apiGroup, resource, "")
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: failed to map resource: %v", b.Name(), err)
+		}
+		attrs.Resource, attrs.Group = r.Resource, r.GVK.Group
+	}
+
+	ctx := t.Local(addon.GoCtxKey).(context.Context)
+	status, err := m.selfSubjectAccessReview(ctx, attrs)
+	if err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+	if len(status.EvaluationError) > 0 {
+		logrus.Debugf("SelfSubjectAccessReview evaluation error: %v", status.EvaluationError)
+	}
+	return starlark.Bool(status.Allowed), nil
+}
+
+func splitSubresource(resource string) (string, string) {
+	if i := strings.Index(resource, "/"); i >= 0 {
+		return resource[:i], resource[i+1:]
+	}
+	return resource, ""
+}
+
+// selfSubjectAccessReview asks the API server whether the current user may act on the resource.
+func (m *kubePackage) selfSubjectAccessReview(ctx context.Context, attrs *authorizationv1.ResourceAttributes) (*authorizationv1.SubjectAccessReviewStatus, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: authorizationv1.SchemeGroupVersion.String(),
+			Kind:       "SelfSubjectAccessReview",
+		},
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.Master+selfSubjectAccessReviewPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	logrus.Debugf("POST to %s", req.URL)
+	resp, err := m.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body (response code: %d): %v", resp.StatusCode, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("SelfSubjectAccessReview failed (response code: %d): %s", resp.StatusCode, raw)
+	}
+	if err := json.Unmarshal(raw, review); err != nil {
+		return nil, fmt.Errorf("failed to parse SelfSubjectAccessReview: %v", err)
+	}
+	return &review.Status, nil
+}
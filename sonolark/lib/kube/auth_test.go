@@ -0,0 +1,105 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// fakeAuthServer allows everything for the "admin" user and only reading configmaps for everyone else.
+func fakeAuthServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("Impersonate-User")
+		if user == "" {
+			user = "admin"
+		}
+		groups := strings.Join(r.Header.Values("Impersonate-Group"), ",")
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == selfSubjectAccessReviewPath:
+			review := &authorizationv1.SelfSubjectAccessReview{}
+			if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = user == "admin" || groups == "admins" ||
+				(attrs.Resource == "configmaps" && attrs.Group == "" && attrs.Verb == "get" && attrs.Subresource == "")
+			json.NewEncoder(w).Encode(review)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/b/configmaps/c":
+			fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "c", "namespace": "b", "annotations": {"user": %q}}}`, user)
+		default:
+			http.Error(w, "unexpected request", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestKubeAuth(t *testing.T) {
+	s := fakeAuthServer(t)
+	defer s.Close()
+
+	config := &rest.Config{Host: s.URL}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatalf("Failed to create transport: %v", err)
+	}
+	pkg := New(s.URL, fakeDiscovery(), dynamic.NewForConfigOrDie(config), &http.Client{Transport: transport}, config, false, false, false, nil)
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Allowed for current user", expr: `kube.can_i("delete", "pods", namespace="b")`, want: "True"},
+		{desc: "Impersonated user is denied", expr: `kube.as_user("alice").can_i("delete", "pods", namespace="b")`, want: "False"},
+		{desc: "Impersonated user is allowed", expr: `kube.as_user("alice").can_i("get", "configmap", namespace="b")`, want: "True"},
+		{desc: "Subresources are not matched by the resource", expr: `kube.as_user("alice").can_i("get", "configmaps/status", namespace="b")`, want: "False"},
+		{desc: "Groups are impersonated", expr: `kube.as_user("alice", groups=["admins"]).can_i("delete", "*")`, want: "True"},
+		{desc: "Requests impersonate the user", expr: `kube.as_user("alice").get(configmap="b/c", object=True).metadata.annotations.user`, want: `"alice"`},
+		{desc: "Original module is unchanged", expr: `kube.get(configmap="b/c", object=True).metadata.annotations.user`, want: `"admin"`},
+		{desc: "Empty user", expr: `kube.as_user("")`, wantErr: "<kube.as_user>: user must not be empty"},
+		{desc: "Unknown resource", expr: `kube.can_i("get", "bogus")`, wantErr: "<kube.can_i>: failed to map resource"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, _, err := Eval("test", tc.expr, nil, starlark.StringDict{"kube": pkg["kube"]})
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+)
+
+// kubeContextFn is an entry point for the `kube.context` built-in which returns the kube module for
+// one of the clusters registered by name, e.g. kube.context("prod-east").get(...).
+func (m *kubePackage) kubeContextFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &name); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+
+	pkg, ok := m.contexts[name]
+	if !ok {
+		names := make([]string, 0, len(m.contexts))
+		for n := range m.contexts {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("<%v>: unknown context %q, expected one of [ %v ]", b.Name(), name, strings.Join(names, " | "))
+	}
+	return pkg.module(), nil
+}
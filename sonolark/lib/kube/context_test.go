@@ -0,0 +1,123 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// fakeCluster returns a cluster whose only object is the kube-system/cluster-info configmap
+// containing the given name.
+func fakeCluster(t *testing.T, name string) (Cluster, func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/kube-system/configmaps/cluster-info" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "cluster-info", "namespace": "kube-system"}, "data": {"name": %q}}`, name)
+	}))
+
+	config := &rest.Config{Host: s.URL}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatalf("Failed to create transport: %v", err)
+	}
+	return Cluster{
+		Addr:       s.URL,
+		Discovery:  fakeDiscovery(),
+		Dynamic:    dynamic.NewForConfigOrDie(config),
+		HTTPClient: &http.Client{Transport: transport},
+		Config:     config,
+	}, s.Close
+}
+
+func TestKubeContext(t *testing.T) {
+	def, closeDef := fakeCluster(t, "default")
+	defer closeDef()
+	east, closeEast := fakeCluster(t, "prod-east")
+	defer closeEast()
+	west, closeWest := fakeCluster(t, "prod-west")
+	defer closeWest()
+
+	pkg := NewMultiCluster(def, map[string]Cluster{"prod-east": east, "prod-west": west}, false, false, false, nil)
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Default cluster", expr: `kube.get(configmap="kube-system/cluster-info", object=True).data.name`, want: `"default"`},
+		{desc: "Named context", expr: `kube.context("prod-east").get(configmap="kube-system/cluster-info", object=True).data.name`, want: `"prod-east"`},
+		{desc: "Contexts are reachable from other contexts", expr: `kube.context("prod-east").context("prod-west").get(configmap="kube-system/cluster-info", object=True).data.name`, want: `"prod-west"`},
+		{desc: "Unknown context", expr: `kube.context("prod-north")`, wantErr: `<kube.context>: unknown context "prod-north", expected one of [ prod-east | prod-west ]`},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, _, err := Eval("test", tc.expr, nil, starlark.StringDict{"kube": pkg["kube"]})
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestKubeContextStepBudget(t *testing.T) {
+	def, closeDef := fakeCluster(t, "default")
+	defer closeDef()
+	east, closeEast := fakeCluster(t, "prod-east")
+	defer closeEast()
+	pkg := NewMultiCluster(def, map[string]Cluster{"prod-east": east}, false, false, false, nil)
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		wantErr string
+	}{
+		{desc: "Context modules", expr: `kube.context("prod-east").get(configmap="kube-system/cluster-info", object=True)`, wantErr: "kube.get: script exceeded its budget of 1 steps"},
+		{desc: "Object methods", expr: `kube.get(configmap="kube-system/cluster-info", object=True).get("data")`, wantErr: "get: script exceeded its budget of 1 steps"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			thread := &starlark.Thread{}
+			shared.SetGoCtx(thread, context.Background())
+			shared.SetGoCtxWithValues(thread, shared.StepBudgetCtxKey, &shared.StepBudget{Max: 1})
+			_, err := starlark.Eval(thread, "test", tc.expr, starlark.StringDict{"kube": pkg["kube"]})
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("Expected error %q but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
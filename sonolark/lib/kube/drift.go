@@ -0,0 +1,396 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cruise-automation/isopod/pkg/addon"
+	"github.com/cruise-automation/isopod/pkg/kpath"
+	"github.com/k14s/starlark-go/starlark"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+var (
+	// defaultDriftFilters are fields which are set by the API server or controllers (or are write-only)
+	// so never match a manifest. They are removed before comparing, along with the diffFilters.
+	defaultDriftFilters = []string{
+		"status",
+		"stringData",
+		"metadata.selfLink",
+		"metadata.uid",
+		"metadata.generation",
+		"metadata.resourceVersion",
+		"metadata.creationTimestamp",
+		"metadata.managedFields",
+		`metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`,
+	}
+)
+
+// fieldDiff is a single field whose live value differs from the manifest.
+type fieldDiff struct {
+	Path    string
+	Desired interface{}
+	Live    interface{}
+}
+
+// kubeDriftFn is an entry point for the `kube.drift` built-in. For each manifest it fetches the live
+// object and compares every field set in the manifest, after removing the filtered fields. It returns
+// a kube.object for each manifest describing the drift and records a test for each one, which fails
+// if the object has drifted or does not exist.
+func (m *kubePackage) kubeDriftFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var manifests starlark.Iterable
+	ignore := &starlark.List{}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "manifests", &manifests, "ignore?", &ignore); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+
+	var filters [][]string
+	userFilters := append([]string{}, m.diffFilters...)
+	for i := 0; i < ignore.Len(); i++ {
+		s, ok := starlark.AsString(ignore.Index(i))
+		if !ok {
+			return nil, fmt.Errorf("<%v>: expected string values for `ignore' arg, got: %s", b.Name(), ignore.Index(i).Type())
+		}
+		userFilters = append(userFilters, s)
+	}
+	for _, f := range append(defaultDriftFilters, userFilters...) {
+		path, err := kpath.Split(f)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: failed to parse diff filter (\"%s\"): %v", b.Name(), f, err)
+		}
+		filters = append(filters, path)
+	}
+
+	ctx := t.Local(addon.GoCtxKey).(context.Context)
+	var results []starlark.Value
+	iter := manifests.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for i := 0; iter.Next(&item); i++ {
+		desired, err := manifestToMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: item %d: %v", b.Name(), i, err)
+		}
+		result, err := m.drift(ctx, desired, filters)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: item %d: %v", b.Name(), i, err)
+		}
+		reportDrift(t, result)
+
+		v, err := toStarlarkValue(result)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: item %d: %v", b.Name(), i, err)
+		}
+		results = append(results, v)
+	}
+	return starlark.NewList(results), nil
+}
+
+// manifestToMap converts a YAML/JSON string or a kube.object into JSON-compatible data.
+func manifestToMap(v starlark.Value) (map[string]interface{}, error) {
+	switch t := v.(type) {
+	case starlark.String:
+		obj, err := newObjectFromString(string(t))
+		if err != nil {
+			return nil, fmt.Errorf("not a YAML string: %v", err)
+		}
+		return obj.data, nil
+	case *Object:
+		return normalizeData(t.data)
+	default:
+		return nil, fmt.Errorf("expected a YAML string or kube.object, got: %s", v.Type())
+	}
+}
+
+// normalizeData round trips data through JSON so that live and desired values have the same types.
+func normalizeData(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return normalizeJSON(out).(map[string]interface{}), nil
+}
+
+// drift compares the desired object with its live state and returns a description of the differences.
+func (m *kubePackage) drift(ctx context.Context, desired map[string]interface{}, filters [][]string) (map[string]interface{}, error) {
+	u := &unstructured.Unstructured{Object: desired}
+	gvk := u.GroupVersionKind()
+	if gvk.Kind == "" || u.GetName() == "" {
+		return nil, fmt.Errorf("manifest must set apiVersion, kind and metadata.name")
+	}
+
+	r, err := newResourceForKind(m.dClient, u.GetName(), u.GetNamespace(), "", gvk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map resource: %v", err)
+	}
+
+	result := map[string]interface{}{
+		"object":     r.String(),
+		"apiVersion": gvk.GroupVersion().String(),
+		"kind":       gvk.Kind,
+		"name":       r.Name,
+		"namespace":  r.Namespace,
+		"missing":    false,
+		"drifted":    false,
+		"diffs":      []interface{}{},
+	}
+
+	live, err := m.kubeGet(ctx, r, 0)
+	if err == ErrNotFound {
+		result["missing"] = true
+		result["drifted"] = true
+		return result, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", r, err)
+	}
+
+	liveData, err := runtimeToMap(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %v: %v", r, err)
+	}
+	// The kind and version were already matched when mapping the resource.
+	delete(desired, "apiVersion")
+	delete(desired, "kind")
+	for _, f := range filters {
+		removePath(desired, f)
+		removePath(liveData, f)
+	}
+
+	diffs := diffFields("", nil, desired, liveData, nil)
+	if gvk.Kind == "Secret" {
+		for i := range diffs {
+			diffs[i].Desired, diffs[i].Live = redact(diffs[i].Desired), redact(diffs[i].Live)
+		}
+	}
+	var out []interface{}
+	for _, d := range diffs {
+		out = append(out, map[string]interface{}{"path": d.Path, "desired": d.Desired, "live": d.Live})
+	}
+	if len(out) > 0 {
+		result["drifted"] = true
+		result["diffs"] = out
+	}
+	return result, nil
+}
+
+func runtimeToMap(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return normalizeData(u.Object)
+	}
+	return normalizeData(obj)
+}
+
+func redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return "<redacted>"
+}
+
+// removePath deletes the field at the path from the data, if it exists.
+func removePath(data map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	if len(path) == 1 {
+		delete(data, path[0])
+		return
+	}
+	switch next := data[path[0]].(type) {
+	case map[string]interface{}:
+		removePath(next, path[1:])
+	case []interface{}:
+		if i, err := strconv.Atoi(path[1]); err == nil && i >= 0 && i < len(next) {
+			if m, ok := next[i].(map[string]interface{}); ok {
+				removePath(m, path[2:])
+			}
+		}
+	}
+}
+
+// diffFields returns the fields set in desired whose value differs in live. Fields only present in
+// live are ignored since they are usually defaults set by the API server. Lists of different lengths
+// are reported as a single difference. keys holds the map keys leading to desired, without list
+// indexes, and scalars are compared with valuesEqual.
+func diffFields(path string, keys []string, desired, live interface{}, diffs []fieldDiff) []fieldDiff {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return append(diffs, fieldDiff{Path: path, Desired: desired, Live: live})
+		}
+		for _, k := range sortedKeys(d) {
+			diffs = diffFields(joinPath(path, k), append(keys[:len(keys):len(keys)], k), d[k], l[k], diffs)
+		}
+		return diffs
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return append(diffs, fieldDiff{Path: path, Desired: desired, Live: live})
+		}
+		for i := range d {
+			diffs = diffFields(fmt.Sprintf("%v[%d]", path, i), keys, d[i], l[i], diffs)
+		}
+		return diffs
+	default:
+		if !valuesEqual(keys, desired, live) {
+			return append(diffs, fieldDiff{Path: path, Desired: desired, Live: live})
+		}
+		return diffs
+	}
+}
+
+// quantityMaps lists the parent and map keys of the maps whose values are resource quantities:
+// container and PVC resources, ResourceQuota hard and used limits and LimitRange limits.
+var quantityMaps = [][2]string{
+	{"resources", "requests"},
+	{"resources", "limits"},
+	{"spec", "hard"},
+	{"status", "hard"},
+	{"status", "used"},
+	{"limits", "default"},
+	{"limits", "defaultRequest"},
+	{"limits", "max"},
+	{"limits", "min"},
+	{"limits", "maxLimitRequestRatio"},
+}
+
+// isQuantity reports whether the field at keys is a value of one of the quantityMaps.
+func isQuantity(keys []string) bool {
+	if len(keys) < 3 {
+		return false
+	}
+	parent := [2]string{keys[len(keys)-3], keys[len(keys)-2]}
+	for _, m := range quantityMaps {
+		if m == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesEqual compares scalar values exactly, except for resource quantities which the API server
+// canonicalizes. Those are compared by value so that e.g. cpu "1000m" matches "1" and memory "1Gi"
+// matches 1073741824.
+func valuesEqual(keys []string, desired, live interface{}) bool {
+	if reflect.DeepEqual(desired, live) {
+		return true
+	}
+	if !isQuantity(keys) {
+		return false
+	}
+	dq, ok := toQuantity(desired)
+	if !ok {
+		return false
+	}
+	lq, ok := toQuantity(live)
+	return ok && dq.Cmp(lq) == 0
+}
+
+func toQuantity(v interface{}) (resource.Quantity, bool) {
+	var s string
+	switch t := v.(type) {
+	case string:
+		s = t
+	case int:
+		s = strconv.Itoa(t)
+	case int32:
+		s = strconv.FormatInt(int64(t), 10)
+	case int64:
+		s = strconv.FormatInt(t, 10)
+	case float64:
+		s = strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return resource.Quantity{}, false
+	}
+	q, err := resource.ParseQuantity(s)
+	return q, err == nil
+}
+
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, `.[]"`) {
+		return fmt.Sprintf("%v[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// reportDrift records a test for the object on a child thread so that any test running on t is
+// unaffected.
+func reportDrift(t *starlark.Thread, result map[string]interface{}) {
+	child := shared.NewChildThread(t, "kube.drift")
+	shared.SetGoCtxWithValues(child, sonobuoy.CurrentTestCtxKey, "")
+	sonobuoy.StartTest(child, fmt.Sprintf("drift: %v", result["object"]))
+
+	switch {
+	case result["missing"] == true:
+		sonobuoy.FailTest(child, fmt.Sprintf("%v does not exist", result["object"]))
+	case result["drifted"] == true:
+		diffs := result["diffs"].([]interface{})
+		lines := []string{fmt.Sprintf("%v has drifted from its manifest in %v field(s):", result["object"], len(diffs))}
+		for _, d := range diffs {
+			d := d.(map[string]interface{})
+			lines = append(lines, fmt.Sprintf("- %v: expected %v, got %v", d["path"], formatValue(d["desired"]), formatValue(d["live"])))
+		}
+		shared.AddFailureDetails(child, map[string]interface{}{
+			"assertion": "kube.drift",
+			"object":    result["object"],
+			"diffs":     diffs,
+		})
+		sonobuoy.FailTest(child, strings.Join(lines, "\n"))
+	default:
+		sonobuoy.PassTest(child, "")
+	}
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/kylelemons/godebug/pretty"
+	sono "github.com/vmware-tanzu/sonobuoy-plugins/plugin-helper"
+
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+func TestDiffFields(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		desired map[string]interface{}
+		live    map[string]interface{}
+		filters [][]string
+		want    []fieldDiff
+	}{
+		{
+			desc:    "Fields only in live are ignored",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": 3, "paused": false}},
+		}, {
+			desc:    "Changed and missing fields are reported",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3, "paused": true}},
+			live:    map[string]interface{}{"spec": map[string]interface{}{"replicas": 2}},
+			want: []fieldDiff{
+				{Path: "spec.paused", Desired: true},
+				{Path: "spec.replicas", Desired: 3, Live: 2},
+			},
+		}, {
+			desc: "Lists are compared item by item",
+			desired: map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "nginx:1.21"},
+			}},
+			live: map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "a", "image": "nginx:1.20"},
+			}},
+			want: []fieldDiff{{Path: "containers[0].image", Desired: "nginx:1.21", Live: "nginx:1.20"}},
+		}, {
+			desc:    "Lists of different lengths are reported whole",
+			desired: map[string]interface{}{"args": []interface{}{"a"}},
+			live:    map[string]interface{}{"args": []interface{}{"a", "b"}},
+			want:    []fieldDiff{{Path: "args", Desired: []interface{}{"a"}, Live: []interface{}{"a", "b"}}},
+		}, {
+			desc:    "Keys with dots are quoted",
+			desired: map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "web"}},
+			live:    map[string]interface{}{"labels": map[string]interface{}{}},
+			want:    []fieldDiff{{Path: `labels["app.kubernetes.io/name"]`, Desired: "web"}},
+		}, {
+			desc: "Canonicalized resource quantities are equal",
+			desired: map[string]interface{}{
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "1000m", "memory": "1Gi"},
+					"limits":   map[string]interface{}{"cpu": "0.5", "nvidia.com/gpu": 1},
+				},
+				"spec": map[string]interface{}{"hard": map[string]interface{}{"pods": "10", "requests.cpu": "2000m"}},
+				"limits": []interface{}{map[string]interface{}{
+					"default":        map[string]interface{}{"memory": "512Mi"},
+					"defaultRequest": map[string]interface{}{"cpu": "100m"},
+				}},
+			},
+			live: map[string]interface{}{
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "1", "memory": int64(1073741824)},
+					"limits":   map[string]interface{}{"cpu": "500m", "nvidia.com/gpu": "1"},
+				},
+				"spec": map[string]interface{}{"hard": map[string]interface{}{"pods": int64(10), "requests.cpu": "2"}},
+				"limits": []interface{}{map[string]interface{}{
+					"default":        map[string]interface{}{"memory": int64(536870912)},
+					"defaultRequest": map[string]interface{}{"cpu": "0.1"},
+				}},
+			},
+		}, {
+			desc: "Different resource quantities are reported",
+			desired: map[string]interface{}{"resources": map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": "500m", "memory": "1Gi"},
+			}},
+			live: map[string]interface{}{"resources": map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": "1", "memory": "1G"},
+			}},
+			want: []fieldDiff{
+				{Path: "resources.limits.cpu", Desired: "500m", Live: "1"},
+				{Path: "resources.limits.memory", Desired: "1Gi", Live: "1G"},
+			},
+		}, {
+			desc: "Values outside resource quantities are compared exactly",
+			desired: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"version": "2", "tier": "010"}},
+				"env":      []interface{}{map[string]interface{}{"name": "CPU", "value": "1000m"}},
+				"ports":    []interface{}{map[string]interface{}{"targetPort": "8080"}},
+			},
+			live: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"version": "2.0", "tier": "10"}},
+				"env":      []interface{}{map[string]interface{}{"name": "CPU", "value": "1"}},
+				"ports":    []interface{}{map[string]interface{}{"targetPort": int64(8080)}},
+			},
+			want: []fieldDiff{
+				{Path: "env[0].value", Desired: "1000m", Live: "1"},
+				{Path: "metadata.labels.tier", Desired: "010", Live: "10"},
+				{Path: "metadata.labels.version", Desired: "2", Live: "2.0"},
+				{Path: "ports[0].targetPort", Desired: "8080", Live: int64(8080)},
+			},
+		}, {
+			desc:    "Strings which are not quantities are compared exactly",
+			desired: map[string]interface{}{"image": "nginx", "enabled": true, "name": "http"},
+			live:    map[string]interface{}{"image": "nginx:1.21", "enabled": "true", "name": int64(80)},
+			want: []fieldDiff{
+				{Path: "enabled", Desired: true, Live: "true"},
+				{Path: "image", Desired: "nginx", Live: "nginx:1.21"},
+				{Path: "name", Desired: "http", Live: int64(80)},
+			},
+		}, {
+			desc:    "Filtered fields are not compared",
+			desired: map[string]interface{}{"status": map[string]interface{}{"ready": 1}, "metadata": map[string]interface{}{"annotations": map[string]interface{}{"a.b/c": "x"}}},
+			live:    map[string]interface{}{"status": map[string]interface{}{"ready": 0}, "metadata": map[string]interface{}{"annotations": map[string]interface{}{"a.b/c": "y"}}},
+			filters: [][]string{{"status"}, {"metadata", "annotations", "a.b/c"}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			for _, f := range tc.filters {
+				removePath(tc.desired, f)
+				removePath(tc.live, f)
+			}
+			got := diffFields("", nil, tc.desired, tc.live, nil)
+			if diff := pretty.Compare(tc.want, got); diff != "" {
+				t.Errorf("Unexpected diffs:\n%v", diff)
+			}
+		})
+	}
+}
+
+const driftConfigMap = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+data:
+  mode: %s
+`
+
+func TestKubeDrift(t *testing.T) {
+	pkg, closeFn, err := NewFake(false)
+	if err != nil {
+		t.Fatalf("Failed to create fake kube: %v", err)
+	}
+	defer closeFn()
+
+	thread := &starlark.Thread{}
+	shared.SetGoCtx(thread, context.Background())
+	sonobuoy.StartSuite(thread, -1)
+
+	src := `
+def run():
+  live = CM % "fast"
+  kube.put(name="settings", namespace="default", data=[live])
+  sonobuoy.startTest("outer")
+  results = kube.drift([CM % "fast", CM % "slow", CM.replace("settings", "missing") % "fast"])
+  sonobuoy.passTest()
+  return results
+
+results = run()
+`
+	predeclared := starlark.StringDict{
+		"kube":     pkg["kube"],
+		"sonobuoy": sonobuoy.API["sonobuoy"],
+		"CM":       starlark.String(driftConfigMap),
+	}
+	globals, err := starlark.ExecFile(thread, "test.star", src, predeclared)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results := globals["results"].(*starlark.List)
+	var got []string
+	for i := 0; i < results.Len(); i++ {
+		r := results.Index(i).(*Object)
+		got = append(got, r.data["name"].(string)+":"+formatValue(r.data["drifted"])+":"+formatValue(r.data["diffs"]))
+	}
+	want := []string{
+		"settings:false:[]",
+		`settings:true:[{"desired":"slow","live":"fast","path":"data.mode"}]`,
+		"missing:true:[]",
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("Unexpected results:\n%v", diff)
+	}
+
+	w := shared.GetGoCtx(thread).Value(sonobuoy.WriterCtxKey).(*sono.SonobuoyResultsWriter)
+	var tests []string
+	for _, item := range w.Data.Items {
+		tests = append(tests, item.Name+"="+item.Status)
+	}
+	wantTests := []string{
+		"drift: configmap.v1 `default/settings'=passed",
+		"drift: configmap.v1 `default/settings'=failed",
+		"drift: configmap.v1 `default/missing'=failed",
+		"outer=passed",
+	}
+	if diff := pretty.Compare(wantTests, tests); diff != "" {
+		t.Errorf("Unexpected tests:\n%v", diff)
+	}
+	if failures, _ := w.Data.Items[1].Details["failures"].([]map[string]interface{}); len(failures) != 1 {
+		t.Errorf("Expected drift to be recorded in the failure details but got %v", w.Data.Items[1].Details)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cruise-automation/isopod/pkg/addon"
+	"github.com/k14s/starlark-go/starlark"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// kubeEventsFn is an entry point for the `kube.events` built-in which lists core/v1 events, oldest first.
+// Events may be limited to those about an object (a kube.object or a "Kind/namespace/name" string), of a
+// type (e.g. "Warning") or seen within a duration (e.g. since="10m"). The namespace defaults to that of
+// the involved object; otherwise events in all namespaces are listed.
+func (m *kubePackage) kubeEventsFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var involved starlark.Value = starlark.None
+	var namespace, since, eventType string
+	unpacked := []interface{}{
+		"involved_object?", &involved,
+		"namespace?", &namespace,
+		"since?", &since,
+		"type?", &eventType,
+	}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+
+	selector := fields.Set{}
+	if involved != starlark.None {
+		ref, err := involvedObjectRef(involved)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: invalid `involved_object' arg: %v", b.Name(), err)
+		}
+		selector["involvedObject.kind"] = ref.Kind
+		selector["involvedObject.name"] = ref.Name
+		if len(ref.Namespace) > 0 {
+			selector["involvedObject.namespace"] = ref.Namespace
+			if len(namespace) == 0 {
+				namespace = ref.Namespace
+			}
+		}
+		if len(ref.UID) > 0 {
+			selector["involvedObject.uid"] = string(ref.UID)
+		}
+	}
+	if len(eventType) > 0 {
+		selector["type"] = eventType
+	}
+
+	var cutoff time.Time
+	if len(since) > 0 {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: failed to parse duration value: %v", b.Name(), err)
+		}
+		cutoff = time.Now().Add(-d)
+	}
+
+	p := "/api/v1/events"
+	if len(namespace) > 0 {
+		p = path.Join("/api/v1/namespaces", namespace, "events")
+	}
+	u := m.Master + p
+	if len(selector) > 0 {
+		u += "?" + url.Values{"fieldSelector": []string{selector.AsSelector().String()}}.Encode()
+	}
+
+	ctx := t.Local(addon.GoCtxKey).(context.Context)
+	obj, found, err := m.kubePeek(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("<%v>: failed to list events: %v", b.Name(), err)
+	}
+	if !found {
+		return starlark.NewList(nil), nil
+	}
+	list, ok := obj.(*corev1.EventList)
+	if !ok {
+		return nil, fmt.Errorf("<%v>: expected an EventList but got %T", b.Name(), obj)
+	}
+
+	var events []corev1.Event
+	for _, e := range list.Items {
+		if !cutoff.IsZero() && eventTime(e).Before(cutoff) {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).Before(eventTime(events[j])) })
+
+	out := make([]starlark.Value, 0, len(events))
+	for i := range events {
+		o, err := newObjectFromRuntime(&events[i], corev1.SchemeGroupVersion.WithKind("Event"))
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: failed to convert event: %v", b.Name(), err)
+		}
+		out = append(out, o)
+	}
+	return starlark.NewList(out), nil
+}
+
+// involvedObjectRef returns a reference to the object given as a kube.object or a string of the form
+// "Kind/namespace/name" or "Kind/name".
+func involvedObjectRef(v starlark.Value) (*corev1.ObjectReference, error) {
+	switch t := v.(type) {
+	case *Object:
+		md, _ := t.data["metadata"].(map[string]interface{})
+		ref := &corev1.ObjectReference{}
+		ref.Kind, _ = t.data["kind"].(string)
+		ref.Name, _ = md["name"].(string)
+		ref.Namespace, _ = md["namespace"].(string)
+		uid, _ := md["uid"].(string)
+		ref.UID = types.UID(uid)
+		if len(ref.Kind) == 0 || len(ref.Name) == 0 {
+			return nil, fmt.Errorf("object must have a kind and metadata.name")
+		}
+		return ref, nil
+	case starlark.String:
+		parts := strings.Split(string(t), "/")
+		switch len(parts) {
+		case 2:
+			return &corev1.ObjectReference{Kind: parts[0], Name: parts[1]}, nil
+		case 3:
+			return &corev1.ObjectReference{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+		}
+		return nil, fmt.Errorf("expected \"Kind/namespace/name\" or \"Kind/name\", got %q", string(t))
+	default:
+		return nil, fmt.Errorf("expected a kube.object or string, got: %s", v.Type())
+	}
+}
+
+// eventTime returns the last time the event was seen.
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	case !e.FirstTimestamp.IsZero():
+		return e.FirstTimestamp.Time
+	}
+	return e.CreationTimestamp.Time
+}
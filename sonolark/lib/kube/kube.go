@@ -33,9 +33,10 @@ import (
 	"time"
 
 	"github.com/cruise-automation/isopod/pkg/addon"
-	log "github.com/golang/glog"
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -70,6 +71,8 @@ type kubePackage struct {
 	diffFilters []string
 	// host:port of the master endpoint.
 	Master string
+	// contexts are the other clusters available via kube.context, keyed by name.
+	contexts map[string]*kubePackage
 }
 
 // KubeNoop returns a new stringDict with noop methods.
@@ -85,6 +88,15 @@ func KubeNoop() starlark.StringDict {
 				kubeGetMethod:              starlark.NewBuiltin("kube."+kubeGetMethod, NoOp),
 				kubeFromStrMethod:          starlark.NewBuiltin("kube."+kubeFromStrMethod, NoOp),
 				kubeFromIntMethod:          starlark.NewBuiltin("kube."+kubeFromIntMethod, NoOp),
+				kubeDiffMethod:             starlark.NewBuiltin("kube."+kubeDiffMethod, NoOp),
+				kubePortForwardMethod:      starlark.NewBuiltin("kube."+kubePortForwardMethod, NoOp),
+				kubeObjectMethod:           starlark.NewBuiltin("kube."+kubeObjectMethod, NoOp),
+				kubeDriftMethod:            starlark.NewBuiltin("kube."+kubeDriftMethod, NoOp),
+				kubeAsUserMethod:           starlark.NewBuiltin("kube."+kubeAsUserMethod, NoOp),
+				kubeCanIMethod:             starlark.NewBuiltin("kube."+kubeCanIMethod, NoOp),
+				kubeContextMethod:          starlark.NewBuiltin("kube."+kubeContextMethod, NoOp),
+				kubeEventsMethod:           starlark.NewBuiltin("kube."+kubeEventsMethod, NoOp),
+				kubeTopMethod:              starlark.NewBuiltin("kube."+kubeTopMethod, NoOp),
 			},
 		},
 	}
@@ -104,37 +116,85 @@ func New(
 	dryRun, force, diff bool,
 	diffFilters []string,
 ) starlark.StringDict {
+	return NewMultiCluster(
+		Cluster{Addr: addr, Discovery: d, Dynamic: dynC, HTTPClient: c, Config: config},
+		nil,
+		dryRun, force, diff,
+		diffFilters,
+	)
+}
 
-	pkg := &kubePackage{
-		dClient:     d,
-		dynClient:   dynC,
-		httpClient:  c,
-		config:      config,
-		Master:      addr,
-		dryRun:      dryRun,
-		force:       force,
-		diff:        diff,
-		diffFilters: diffFilters,
+// Cluster holds the clients used to access a single cluster.
+type Cluster struct {
+	// host:port of the master endpoint.
+	Addr       string
+	Discovery  discovery.DiscoveryInterface
+	Dynamic    dynamic.Interface
+	HTTPClient *http.Client
+	Config     *rest.Config
+}
+
+// NewMultiCluster returns a new StringDict whose kube module accesses the default cluster. The
+// other clusters are available to scripts by name via kube.context(name).
+func NewMultiCluster(
+	def Cluster,
+	contexts map[string]Cluster,
+	dryRun, force, diff bool,
+	diffFilters []string,
+) starlark.StringDict {
+	newPkg := func(c Cluster) *kubePackage {
+		return &kubePackage{
+			dClient:     c.Discovery,
+			dynClient:   c.Dynamic,
+			httpClient:  c.HTTPClient,
+			config:      c.Config,
+			Master:      c.Addr,
+			dryRun:      dryRun,
+			force:       force,
+			diff:        diff,
+			diffFilters: diffFilters,
+		}
+	}
+
+	pkg := newPkg(def)
+	pkg.contexts = map[string]*kubePackage{}
+	for name, c := range contexts {
+		ctxPkg := newPkg(c)
+		ctxPkg.contexts = pkg.contexts
+		pkg.contexts[name] = ctxPkg
 	}
 
 	return starlark.StringDict{
-		"kube": &starlarkstruct.Module{
-			Name: "kube",
-			Members: starlark.StringDict{
-				kubeDeleteMethod:           starlark.NewBuiltin("kube."+kubeDeleteMethod, pkg.kubeDeleteFn),
-				kubeResourceQuantityMethod: starlark.NewBuiltin("kube."+kubeResourceQuantityMethod, resourceQuantityFn),
-				kubePutMethod:              starlark.NewBuiltin("kube."+kubePutMethod, pkg.kubePutFn),
-				kubeExistsMethod:           starlark.NewBuiltin("kube."+kubeExistsMethod, pkg.kubeExistsFn),
-				kubeGetMethod:              starlark.NewBuiltin("kube."+kubeGetMethod, pkg.kubeGetFn),
-				kubeFromStrMethod:          starlark.NewBuiltin("kube."+kubeFromStrMethod, fromStringFn),
-				kubeFromIntMethod:          starlark.NewBuiltin("kube."+kubeFromIntMethod, fromIntFn),
-				kubeDiffMethod:             starlark.NewBuiltin("kube."+kubeDiffMethod, kubeDiffFn),
-				kubePortForwardMethod:      starlark.NewBuiltin("kube."+kubePortForwardMethod, pkg.kubePortForwardTestFn),
-			},
-		},
+		"kube": pkg.module(),
 	}
 }
 
+// module returns the starlark module for the package. Its builtins check the script's limits since
+// modules returned by kube.context and kube.as_user are built at runtime.
+func (m *kubePackage) module() *starlarkstruct.Module {
+	return shared.GuardModule(&starlarkstruct.Module{
+		Name: "kube",
+		Members: starlark.StringDict{
+			kubeDeleteMethod:           starlark.NewBuiltin("kube."+kubeDeleteMethod, m.kubeDeleteFn),
+			kubeResourceQuantityMethod: starlark.NewBuiltin("kube."+kubeResourceQuantityMethod, resourceQuantityFn),
+			kubePutMethod:              starlark.NewBuiltin("kube."+kubePutMethod, m.kubePutFn),
+			kubeExistsMethod:           starlark.NewBuiltin("kube."+kubeExistsMethod, m.kubeExistsFn),
+			kubeGetMethod:              starlark.NewBuiltin("kube."+kubeGetMethod, m.kubeGetFn),
+			kubeFromStrMethod:          starlark.NewBuiltin("kube."+kubeFromStrMethod, fromStringFn),
+			kubeFromIntMethod:          starlark.NewBuiltin("kube."+kubeFromIntMethod, fromIntFn),
+			kubeDiffMethod:             starlark.NewBuiltin("kube."+kubeDiffMethod, kubeDiffFn),
+			kubePortForwardMethod:      starlark.NewBuiltin("kube."+kubePortForwardMethod, m.kubePortForwardTestFn),
+			kubeObjectMethod:           starlark.NewBuiltin("kube."+kubeObjectMethod, kubeObjectFn),
+			kubeDriftMethod:            starlark.NewBuiltin("kube."+kubeDriftMethod, m.kubeDriftFn),
+			kubeAsUserMethod:           starlark.NewBuiltin("kube."+kubeAsUserMethod, m.kubeAsUserFn),
+			kubeCanIMethod:             starlark.NewBuiltin("kube."+kubeCanIMethod, m.kubeCanIFn),
+			kubeContextMethod:          starlark.NewBuiltin("kube."+kubeContextMethod, m.kubeContextFn),
+			kubeEventsMethod:           starlark.NewBuiltin("kube."+kubeEventsMethod, m.kubeEventsFn),
+			kubeTopMethod:              starlark.NewBuiltin("kube."+kubeTopMethod, m.kubeTopFn),
+		},
+	})
+}
+
 const (
 	kubeDeleteMethod           = "delete"
 	kubeFromIntMethod          = "from_int"
@@ -146,6 +206,13 @@ const (
 	kubeResourceQuantityMethod = "resource_quantity"
 	kubeDiffMethod             = "diff"
 	kubePortForwardMethod      = "portforward"
+	kubeObjectMethod           = "object"
+	kubeDriftMethod            = "drift"
+	kubeAsUserMethod           = "as_user"
+	kubeCanIMethod             = "can_i"
+	kubeContextMethod          = "context"
+	kubeEventsMethod           = "events"
+	kubeTopMethod              = "top"
 )
 
 // setMetadata sets metadata fields on the obj.
@@ -328,6 +395,7 @@ func (m *kubePackage) kubeGetFn(t *starlark.Thread, b *starlark.Builtin, args st
 	var wantJSON bool
 	//wip
 	_ = wantJSON
+	var wantObject bool
 	for _, kv := range kwargs[1:] {
 		switch string(kv[0].(starlark.String)) {
 		case apiGroupKW:
@@ -351,8 +419,14 @@ func (m *kubePackage) kubeGetFn(t *starlark.Thread, b *starlark.Builtin, args st
 				return nil, fmt.Errorf("<%v>: expected boolean value for `json' arg, got: %s", b.Name(), kv[1].Type())
 			}
 			wantJSON = bool(bv)
+		case "object":
+			bv, ok := kv[1].(starlark.Bool)
+			if !ok {
+				return nil, fmt.Errorf("<%v>: expected boolean value for `object' arg, got: %s", b.Name(), kv[1].Type())
+			}
+			wantObject = bool(bv)
 		default:
-			return nil, fmt.Errorf("<%v>: expected one of [ api_group | wait | json ] args, got: %v=%v", b.Name(), kv[0], kv[1])
+			return nil, fmt.Errorf("<%v>: expected one of [ api_group | wait | json | object ] args, got: %v=%v", b.Name(), kv[0], kv[1])
 		}
 	}
 
@@ -367,6 +441,14 @@ func (m *kubePackage) kubeGetFn(t *starlark.Thread, b *starlark.Builtin, args st
 		return nil, fmt.Errorf("<%v>: failed to get %s%s `%s': %v", b.Name(), resource, maybeCore(string(apiGroup)), name, err)
 	}
 
+	if wantObject {
+		o, err := newObjectFromRuntime(obj, r.GVK)
+		if err != nil {
+			return nil, fmt.Errorf("<%v>: failed to convert %s%s `%s' to an object: %v", b.Name(), resource, maybeCore(string(apiGroup)), name, err)
+		}
+		return o, nil
+	}
+
 	bits, err := renderObj(obj, nil, true, m.diffFilters)
 	if err != nil {
 		panic(err)
@@ -471,7 +553,7 @@ func parseHTTPResponse(r *http.Response) (obj runtime.Object, details string, er
 		return nil, "", fmt.Errorf("failed to read body (response code: %d): %v", r.StatusCode, err)
 	}
 
-	log.V(2).Infof("Response raw data: %s", raw)
+	logrus.Tracef("Response raw data: %s", raw)
 	obj, gvk, err := decode(raw)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to parse json object (response code: %d): %v", r.StatusCode, err)
@@ -505,7 +587,7 @@ func (m *kubePackage) kubePeek(ctx context.Context, url string) (obj runtime.Obj
 		return nil, false, err
 	}
 
-	log.V(1).Infof("GET to %s", url)
+	logrus.Debugf("GET to %s", url)
 
 	resp, err := m.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
@@ -627,15 +709,15 @@ func (m *kubePackage) kubeUpdate(ctx context.Context, r *apiResource, obj runtim
 		return err
 	}
 
-	log.V(1).Infof("%s to %s", method, url)
+	logrus.Debugf("%s to %s", method, url)
 
-	if log.V(2) {
-		s, err := renderObj(obj, &r.GVK, bool(log.V(3)) /* If --v=3, only return JSON. */, m.diffFilters)
+	if logrus.IsLevelEnabled(logrus.TraceLevel) {
+		s, err := renderObj(obj, &r.GVK, false, m.diffFilters)
 		if err != nil {
 			return fmt.Errorf("failed to render :live object for %s: %v", r.String(), err)
 		}
 
-		log.Infof("%s:\n%s", r.String(), s)
+		logrus.Infof("%s:\n%s", r.String(), s)
 	}
 
 	if m.diff {
@@ -662,7 +744,7 @@ func (m *kubePackage) kubeUpdate(ctx context.Context, r *apiResource, obj runtim
 	if method == http.MethodPut {
 		actionMsg = "updated"
 	}
-	log.Infof("%s %s", rMsg, actionMsg)
+	logrus.Infof("%s %s", rMsg, actionMsg)
 
 	return nil
 }
@@ -670,7 +752,7 @@ func (m *kubePackage) kubeUpdate(ctx context.Context, r *apiResource, obj runtim
 // kubeDelete deletes namespace/name resource in Kubernetes.
 // Attempts to deduce GroupVersionResource from apiGroup (optional) and resource
 // strings. Fails if multiple matches found.
-func (m *kubePackage) kubeDelete(_ context.Context, r *apiResource, foreground bool) error {
+func (m *kubePackage) kubeDelete(ctx context.Context, r *apiResource, foreground bool) error {
 	var c dynamic.ResourceInterface = m.dynClient.Resource(r.GroupVersionResource())
 	if r.Namespace != "" {
 		c = c.(dynamic.NamespaceableResourceInterface).Namespace(r.Namespace)
@@ -681,19 +763,19 @@ func (m *kubePackage) kubeDelete(_ context.Context, r *apiResource, foreground b
 		delPolicy = metav1.DeletePropagationForeground
 	}
 
-	log.V(1).Infof("DELETE to %s", m.Master+r.PathWithName())
+	logrus.Debugf("DELETE to %s", m.Master+r.PathWithName())
 
 	if m.dryRun {
 		return nil
 	}
 
-	if err := c.Delete(context.TODO(), r.Name, metav1.DeleteOptions{
+	if err := c.Delete(ctx, r.Name, metav1.DeleteOptions{
 		PropagationPolicy: &delPolicy,
 	}); err != nil {
 		return err
 	}
 
-	log.Infof("%v deleted", r)
+	logrus.Infof("%v deleted", r)
 
 	return nil
 }
@@ -742,19 +824,28 @@ func (m *kubePackage) kubeGet(ctx context.Context, r *apiResource, wait time.Dur
 	// not reachable
 }
 
+// kubePortForwardTestFn is an entry point for the `kube.portforward` built-in which forwards the local port
+// to the pod. If `test_path' is given it returns whether the path can be reached via HTTP. If `fn' is given
+// it is called with the local address (e.g. "localhost:8443") while the port is forwarded and its result
+// is returned.
 func (m *kubePackage) kubePortForwardTestFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var namespace, pod, testPath string
 	var localPort, podPort int
+	var fn starlark.Callable
 	unpacked := []interface{}{
 		"namespace", &namespace,
 		"pod", &pod,
 		"local_port", &localPort,
 		"pod_port", &podPort,
-		"test_path", &testPath,
+		"test_path?", &testPath,
+		"fn?", &fn,
 	}
 	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
 		return starlark.False, fmt.Errorf("<%v>: %v", b.Name(), err)
 	}
+	if len(testPath) == 0 && fn == nil {
+		return starlark.False, fmt.Errorf("<%v>: one of `test_path' or `fn' is required", b.Name())
+	}
 
 	// check if test path is already reachable before port forward
 	testUrl := fmt.Sprintf("http://localhost:%v%v", localPort, testPath)
@@ -765,9 +856,11 @@ func (m *kubePackage) kubePortForwardTestFn(t *starlark.Thread, b *starlark.Buil
 	}
 
 	ctx := t.Local(addon.GoCtxKey).(context.Context)
-	_, err = m.httpClient.Do(req.WithContext(ctx))
-	if err == nil {
-		return starlark.False, fmt.Errorf("endpoint %v already in use", testUrl)
+	if len(testPath) > 0 {
+		_, err = m.httpClient.Do(req.WithContext(ctx))
+		if err == nil {
+			return starlark.False, fmt.Errorf("endpoint %v already in use", testUrl)
+		}
 	}
 
 	// port forward the pod
@@ -785,7 +878,7 @@ func (m *kubePackage) kubePortForwardTestFn(t *starlark.Thread, b *starlark.Buil
 	errorWriter := bufio.NewWriter(&errorBuffer)
 	outWriter := bufio.NewWriter(&outBuffer)
 
-	var readyChannel chan struct{}
+	readyChannel := make(chan struct{})
 	portForwardStopChannel := make(chan struct{})
 
 	portForward, err := portforward.New(dialer, []string{fmt.Sprintf("%d:%d", localPort, podPort)}, portForwardStopChannel, readyChannel, outWriter, errorWriter)
@@ -796,11 +889,20 @@ func (m *kubePackage) kubePortForwardTestFn(t *starlark.Thread, b *starlark.Buil
 	go func() {
 		err = portForward.ForwardPorts()
 		if err != nil {
-			log.Fatal(err)
+			logrus.Fatal(err)
 		}
 	}()
 	defer close(portForwardStopChannel)
 
+	if fn != nil {
+		select {
+		case <-readyChannel:
+		case <-ctx.Done():
+			return starlark.None, ctx.Err()
+		}
+		return starlark.Call(t, fn, starlark.Tuple{starlark.String(fmt.Sprintf("localhost:%d", localPort))}, nil)
+	}
+
 	// check if endpoint is accessible after short delay
 	time.Sleep(time.Millisecond * 125)
 
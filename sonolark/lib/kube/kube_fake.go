@@ -27,8 +27,8 @@ import (
 	"reflect"
 	"strings"
 
-	log "github.com/golang/glog"
 	"github.com/k14s/starlark-go/starlark"
+	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/discovery"
@@ -89,7 +89,7 @@ func nameFromObj(obj apiruntime.Object) (string, error) {
 
 func write(w io.Writer, bs []byte) {
 	if _, err := w.Write(bs); err != nil {
-		log.Errorf("failed to write `%v' to response: %v", bs, err)
+		logrus.Errorf("failed to write `%v' to response: %v", bs, err)
 	}
 }
 
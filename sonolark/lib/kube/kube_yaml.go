@@ -21,8 +21,8 @@ import (
 	"os"
 	"strings"
 
-	log "github.com/golang/glog"
 	"github.com/k14s/starlark-go/starlark"
+	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
@@ -30,7 +30,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
-	"github.com/cruise-automation/isopod/pkg/addon"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
 )
 
 // DynamicClient used for applying dynamic resource manifests
@@ -94,7 +94,7 @@ func (m *kubePackage) Apply(t *starlark.Thread, name, namespace string, data *st
 			return nil, fmt.Errorf("failed to validate/apply metadata for object %v/%s => %v", gvk.Kind, name, err)
 		}
 
-		ctx := t.Local(addon.GoCtxKey).(context.Context)
+		ctx := shared.GetGoCtx(t)
 		if err := m.kubeUpdateYaml(ctx, r, obj); err != nil {
 			return nil, err
 		}
@@ -140,13 +140,13 @@ func (m *kubePackage) kubeUpdateYaml(ctx context.Context, r *apiResource, obj ru
 		c = c.(dynamic.NamespaceableResourceInterface).Namespace(r.Namespace)
 	}
 
-	if log.V(2) {
-		s, err := renderObj(obj, &r.GVK, bool(log.V(3)) /* If --v=3, only return JSON. */, m.diffFilters)
+	if logrus.IsLevelEnabled(logrus.TraceLevel) {
+		s, err := renderObj(obj, &r.GVK, false, m.diffFilters)
 		if err != nil {
 			return fmt.Errorf("failed to render :live object for %v: %v", r, err)
 		}
 
-		log.Infof("%v:\n%s", r, s)
+		logrus.Infof("%v:\n%s", r, s)
 	}
 
 	un, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
@@ -156,9 +156,9 @@ func (m *kubePackage) kubeUpdateYaml(ctx context.Context, r *apiResource, obj ru
 
 	var resp *unstructured.Unstructured
 	if found {
-		resp, err = c.Update(context.TODO(), &unstructured.Unstructured{Object: un}, metav1.UpdateOptions{})
+		resp, err = c.Update(ctx, &unstructured.Unstructured{Object: un}, metav1.UpdateOptions{})
 	} else {
-		resp, err = c.Create(context.TODO(), &unstructured.Unstructured{Object: un}, metav1.CreateOptions{})
+		resp, err = c.Create(ctx, &unstructured.Unstructured{Object: un}, metav1.CreateOptions{})
 	}
 	if err != nil {
 		return err
@@ -169,7 +169,7 @@ func (m *kubePackage) kubeUpdateYaml(ctx context.Context, r *apiResource, obj ru
 		return err
 	}
 
-	log.Infof("%s updated", rMsg)
+	logrus.Infof("%s updated", rMsg)
 
 	return err
 }
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// fakeMetricsServer serves a fixed list of events (filtered by type) and the metrics of a pod and node.
+func fakeMetricsServer(t *testing.T) *httptest.Server {
+	now := time.Now().UTC()
+	event := func(name, eventType, reason string, age time.Duration) string {
+		return fmt.Sprintf(`{"metadata": {"name": %q, "namespace": "default"}, "involvedObject": {"kind": "Pod", "namespace": "default", "name": "web"}, "type": %q, "reason": %q, "lastTimestamp": %q}`,
+			name, eventType, reason, now.Add(-age).Format(time.RFC3339))
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/namespaces/default/events":
+			selector := r.URL.Query().Get("fieldSelector")
+			if !strings.Contains(selector, "involvedObject.kind=Pod") || !strings.Contains(selector, "involvedObject.name=web") {
+				http.Error(w, "unexpected field selector: "+selector, http.StatusBadRequest)
+				return
+			}
+			var items []string
+			for _, e := range []struct {
+				name, eventType, reason string
+				age                     time.Duration
+			}{
+				{"recent-warning", "Warning", "FailedScheduling", time.Minute},
+				{"old-warning", "Warning", "BackOff", time.Hour},
+				{"normal", "Normal", "Scheduled", 2 * time.Minute},
+			} {
+				if strings.Contains(selector, "type=") && !strings.Contains(selector, "type="+e.eventType) {
+					continue
+				}
+				items = append(items, event(e.name, e.eventType, e.reason, e.age))
+			}
+			fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "EventList", "items": [%s]}`, strings.Join(items, ","))
+		case metricsAPIPath + "/namespaces/default/pods":
+			fmt.Fprint(w, `{"items": [{"metadata": {"name": "web", "namespace": "default"}, "window": "30s", "containers": [
+				{"name": "app", "usage": {"cpu": "150m", "memory": "100Mi"}},
+				{"name": "sidecar", "usage": {"cpu": "50m", "memory": "28Mi"}}]}]}`)
+		case metricsAPIPath + "/nodes/n1":
+			fmt.Fprint(w, `{"metadata": {"name": "n1"}, "window": "30s", "usage": {"cpu": "2", "memory": "4Gi"}}`)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestKubeEventsAndTop(t *testing.T) {
+	s := fakeMetricsServer(t)
+	defer s.Close()
+
+	config := &rest.Config{Host: s.URL}
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		t.Fatalf("Failed to create transport: %v", err)
+	}
+	pkg := New(s.URL, fakeDiscovery(), dynamic.NewForConfigOrDie(config), &http.Client{Transport: transport}, config, false, false, false, nil)
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Events are sorted oldest first", expr: `[e.metadata.name for e in kube.events(involved_object="Pod/default/web")]`, want: `["old-warning", "normal", "recent-warning"]`},
+		{desc: "Events filtered by type", expr: `[e.reason for e in kube.events(involved_object="Pod/default/web", type="Warning")]`, want: `["BackOff", "FailedScheduling"]`},
+		{desc: "Events filtered by age", expr: `[e.reason for e in kube.events(involved_object="Pod/default/web", since="10m", type="Warning")]`, want: `["FailedScheduling"]`},
+		{desc: "Involved object as kube.object", expr: `len(kube.events(involved_object=kube.object("{\"kind\": \"Pod\", \"metadata\": {\"name\": \"web\", \"namespace\": \"default\"}}")))`, want: "3"},
+		{desc: "Invalid involved object", expr: `kube.events(involved_object="web")`, wantErr: "invalid `involved_object' arg"},
+		{desc: "Invalid duration", expr: `kube.events(since="ten")`, wantErr: "failed to parse duration value"},
+		{desc: "Pod usage is summed over containers", expr: `kube.top("pods", namespace="default")[0].usage.cpu`, want: "200m"},
+		{desc: "Container usage", expr: `[(c.name, str(c.usage.memory)) for c in kube.top("pods", namespace="default")[0].containers]`, want: `[("app", "100Mi"), ("sidecar", "28Mi")]`},
+		{desc: "Usage compares with resource_quantity", expr: `kube.top("pods", namespace="default")[0].usage.memory == kube.resource_quantity("128Mi")`, want: "True"},
+		{desc: "Node by name", expr: `kube.top("nodes", name="n1").usage.cpu > kube.resource_quantity("1500m")`, want: "True"},
+		{desc: "Missing metrics", expr: `kube.top("nodes", name="n2")`, wantErr: "<kube.top>: failed to get node metrics"},
+		{desc: "Unknown kind", expr: `kube.top("services")`, wantErr: "expected one of [ pods | nodes ]"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, _, err := Eval("test", tc.expr, nil, starlark.StringDict{"kube": pkg["kube"]})
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Equal in different units", expr: `kube.resource_quantity("1") == kube.resource_quantity("1000m")`, want: "True"},
+		{desc: "Less than", expr: `kube.resource_quantity("512Mi") < kube.resource_quantity("1Gi")`, want: "True"},
+		{desc: "Addition", expr: `kube.resource_quantity("100m") + kube.resource_quantity("1")`, want: "1100m"},
+		{desc: "Subtraction", expr: `kube.resource_quantity("1Gi") - kube.resource_quantity("512Mi")`, want: "512Mi"},
+		{desc: "Value", expr: `kube.resource_quantity("2Ki").value`, want: "2048"},
+		{desc: "Milli value", expr: `kube.resource_quantity("1.5").milli_value`, want: "1500"},
+		{desc: "Usable as dict key", expr: `{kube.resource_quantity("1"): "a"}[kube.resource_quantity("1000m")]`, want: `"a"`},
+		{desc: "Invalid quantity", expr: `kube.resource_quantity("lots")`, wantErr: "failed to parse quantity string"},
+		{desc: "Cannot add a number", expr: `kube.resource_quantity("1") + 1`, wantErr: "unknown binary op"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, _, err := Eval("test", tc.expr, nil, New("", nil, nil, nil, nil, false, false, false, nil))
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/orderedmap"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	objectPathMethod      = "path"
+	objectConditionMethod = "condition"
+	objectGetMethod       = "get"
+)
+
+// Object wraps a Kubernetes object (or any map within one) so that scripts can use attribute access
+// (e.g. obj.spec.replicas), indexing and JSONPath queries. Missing fields evaluate to None rather than
+// failing the script. Fields take precedence over the methods of the same name.
+type Object struct {
+	data map[string]interface{}
+}
+
+var (
+	_ starlark.HasAttrs = &Object{}
+	_ starlark.Mapping  = &Object{}
+	_ starlark.Iterable = &Object{}
+)
+
+// NewObject returns an Object wrapping the given JSON-compatible data.
+func NewObject(data map[string]interface{}) *Object {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &Object{data: data}
+}
+
+// newObjectFromRuntime converts the runtime object into an Object. The gvk is used to populate
+// the apiVersion and kind if the object does not already have them.
+func newObjectFromRuntime(obj runtime.Object, gvk schema.GroupVersionKind) (*Object, error) {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := data["kind"]; !ok && len(gvk.Kind) > 0 {
+		data["kind"] = gvk.Kind
+		data["apiVersion"] = gvk.GroupVersion().String()
+	}
+	return NewObject(data), nil
+}
+
+// newObjectFromString decodes the YAML or JSON string into an Object.
+func newObjectFromString(s string) (*Object, error) {
+	data := map[string]interface{}{}
+	j, err := yaml.YAMLToJSON([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(j, &data); err != nil {
+		return nil, err
+	}
+	return NewObject(normalizeJSON(data).(map[string]interface{})), nil
+}
+
+// normalizeJSON converts whole float64 values to int64 so that decoded objects match the
+// values of those converted from typed objects.
+func normalizeJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = normalizeJSON(item)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = normalizeJSON(item)
+		}
+	case float64:
+		if t == float64(int64(t)) {
+			return int64(t)
+		}
+	}
+	return v
+}
+
+// String implements starlark.Value.String by rendering the object as YAML. Secret data is redacted.
+func (o *Object) String() string {
+	data := o.data
+	if data["kind"] == "Secret" {
+		data = redactSecretData(data)
+	}
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf("<kube.object: %v>", err)
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func redactSecretData(data map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range data {
+		out[k] = v
+	}
+	for _, field := range []string{"data", "stringData"} {
+		m, ok := data[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		redacted := map[string]interface{}{}
+		for k := range m {
+			redacted[k] = "<redacted>"
+		}
+		out[field] = redacted
+	}
+	return out
+}
+
+// Type implements starlark.Value.Type.
+func (o *Object) Type() string { return "kube.object" }
+
+// Freeze implements starlark.Value.Freeze. Objects are immutable so this is a no-op.
+func (o *Object) Freeze() {}
+
+// Truth implements starlark.Value.Truth.
+// Returns true if the object is non-empty.
+func (o *Object) Truth() starlark.Bool { return len(o.data) > 0 }
+
+// Hash implements starlark.Value.Hash.
+func (o *Object) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", o.Type()) }
+
+// Attr implements starlark.HasAttrs.Attr.
+func (o *Object) Attr(name string) (starlark.Value, error) {
+	if v, ok := o.data[name]; ok {
+		return toStarlarkValue(v)
+	}
+	switch name {
+	case objectPathMethod:
+		return shared.GuardBuiltin(starlark.NewBuiltin(objectPathMethod, o.pathFn)), nil
+	case objectConditionMethod:
+		return shared.GuardBuiltin(starlark.NewBuiltin(objectConditionMethod, o.conditionFn)), nil
+	case objectGetMethod:
+		return shared.GuardBuiltin(starlark.NewBuiltin(objectGetMethod, o.getFn)), nil
+	}
+	return starlark.None, nil
+}
+
+// AttrNames implements starlark.HasAttrs.AttrNames.
+func (o *Object) AttrNames() []string {
+	names := []string{objectPathMethod, objectConditionMethod, objectGetMethod}
+	for k := range o.data {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get implements starlark.Mapping.Get.
+func (o *Object) Get(k starlark.Value) (v starlark.Value, found bool, err error) {
+	s, ok := k.(starlark.String)
+	if !ok {
+		return nil, false, fmt.Errorf("want string key, got: %v", k.Type())
+	}
+	raw, ok := o.data[string(s)]
+	if !ok {
+		return nil, false, nil
+	}
+	v, err = toStarlarkValue(raw)
+	return v, err == nil, err
+}
+
+// Len implements starlark.Sequence.Len.
+func (o *Object) Len() int { return len(o.data) }
+
+// Iterate implements starlark.Iterable.Iterate by iterating over the sorted keys.
+func (o *Object) Iterate() starlark.Iterator {
+	keys := make([]starlark.String, 0, len(o.data))
+	for k := range o.data {
+		keys = append(keys, starlark.String(k))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return &keysIterator{keys: keys}
+}
+
+// AsGoValue allows objects to be used with the ytt libraries (e.g. assert.equals and yaml.encode).
+func (o *Object) AsGoValue() (interface{}, error) {
+	return toOrderedGoValue(o.data), nil
+}
+
+func toOrderedGoValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		m := orderedmap.NewMap()
+		for _, k := range keys {
+			m.Set(k, toOrderedGoValue(t[k]))
+		}
+		return m
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = toOrderedGoValue(item)
+		}
+		return out
+	}
+	return v
+}
+
+// toStarlarkValue converts JSON-compatible data into starlark values, wrapping maps as Objects.
+func toStarlarkValue(v interface{}) (starlark.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case map[string]interface{}:
+		return NewObject(t), nil
+	case []interface{}:
+		items := make([]starlark.Value, 0, len(t))
+		for i, item := range t {
+			sv, err := toStarlarkValue(item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert item [%d]=%v: %v", i, item, err)
+			}
+			items = append(items, sv)
+		}
+		return starlark.NewList(items), nil
+	case string:
+		return starlark.String(t), nil
+	case bool:
+		return starlark.Bool(t), nil
+	case int64:
+		return starlark.MakeInt64(t), nil
+	case int:
+		return starlark.MakeInt(t), nil
+	case float64:
+		return starlark.Float(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return starlark.MakeInt64(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	default:
+		return nil, fmt.Errorf("unsupported data type: %T", t)
+	}
+}
+
+// pathFn implements obj.path(expr), evaluating a kubectl style JSONPath expression against the object.
+// Returns None if nothing matches, the value if there is a single match or a list of all matches.
+func (o *Object) pathFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var expr string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &expr); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+
+	jp := jsonpath.New(b.Name()).AllowMissingKeys(true)
+	if err := jp.Parse(expr); err != nil {
+		return nil, fmt.Errorf("%v: invalid JSONPath %q: %v", b.Name(), expr, err)
+	}
+	results, err := jp.FindResults(o.data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: failed to evaluate JSONPath %q: %v", b.Name(), expr, err)
+	}
+
+	var matches []starlark.Value
+	for _, result := range results {
+		for _, r := range result {
+			v, err := toStarlarkValue(r.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", b.Name(), err)
+			}
+			matches = append(matches, v)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return starlark.None, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return starlark.NewList(matches), nil
+	}
+}
+
+// conditionFn implements obj.condition(type), returning the entry in .status.conditions with
+// the given type or None if there is no such condition.
+func (o *Object) conditionFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var condType string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &condType); err != nil {
+		return nil, err
+	}
+
+	status, _ := o.data["status"].(map[string]interface{})
+	conditions, _ := status["conditions"].([]interface{})
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == condType {
+			return NewObject(cond), nil
+		}
+	}
+	return starlark.None, nil
+}
+
+// HasAttr replaces the hasattr builtin so that it reports whether a kube.object has a field, since
+// missing fields evaluate to None rather than being absent. Other values use the builtin.
+var HasAttr = starlark.NewBuiltin("hasattr", hasAttrFn)
+
+func hasAttrFn(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &name); err != nil {
+		return nil, err
+	}
+	o, ok := x.(*Object)
+	if !ok {
+		return starlark.Call(thread, starlark.Universe["hasattr"], args, kwargs)
+	}
+	for _, n := range o.AttrNames() {
+		if n == name {
+			return starlark.True, nil
+		}
+	}
+	return starlark.False, nil
+}
+
+// getFn implements obj.get(key, default=None) like dict.get.
+func (o *Object) getFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var dflt starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "default?", &dflt); err != nil {
+		return nil, err
+	}
+	raw, ok := o.data[key]
+	if !ok {
+		return dflt, nil
+	}
+	return toStarlarkValue(raw)
+}
+
+// kubeObjectFn is an entry point for the `kube.object` built-in which converts a YAML or JSON
+// string (such as the output of kube.get) into an Object.
+func kubeObjectFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return nil, err
+	}
+	obj, err := newObjectFromString(s)
+	if err != nil {
+		return nil, fmt.Errorf("<%v>: failed to decode object: %v", b.Name(), err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const testDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.21
+      - name: sidecar
+        image: envoy:1.20
+status:
+  conditions:
+  - type: Available
+    status: "True"
+  - type: Progressing
+    status: "False"
+    reason: ProgressDeadlineExceeded
+`
+
+func TestObject(t *testing.T) {
+	obj, err := newObjectFromString(testDeployment)
+	if err != nil {
+		t.Fatalf("Failed to decode object: %v", err)
+	}
+
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{expr: `obj.spec.replicas`, want: `3`},
+		{expr: `obj.metadata.labels.app`, want: `"web"`},
+		{expr: `obj["metadata"]["name"]`, want: `"web"`},
+		{expr: `obj.spec.template.spec.containers[1].image`, want: `"envoy:1.20"`},
+		{expr: `obj.spec.paused`, want: `None`},
+		{expr: `obj.spec.paused == None`, want: `True`},
+		{expr: `obj.spec.replicas == None`, want: `False`},
+		{expr: `hasattr(obj.spec, "paused")`, want: `False`},
+		{expr: `hasattr(obj.spec, "replicas")`, want: `True`},
+		{expr: `hasattr(obj, "path")`, want: `True`},
+		{expr: `hasattr("text", "upper")`, want: `True`},
+		{expr: `hasattr("text", "missing")`, want: `False`},
+		{expr: `obj.spec.replicas + 1`, want: `4`},
+		{expr: `obj.spec.get("minReadySeconds", 0) + 1`, want: `1`},
+		{expr: `obj.get("kind")`, want: `"Deployment"`},
+		{expr: `obj.spec.get("paused", False)`, want: `False`},
+		{expr: `"replicas" in obj.spec`, want: `True`},
+		{expr: `"paused" in obj.spec`, want: `False`},
+		{expr: `[k for k in obj.metadata]`, want: `["labels", "name", "namespace"]`},
+		{expr: `len(obj.metadata.labels)`, want: `1`},
+		{expr: `obj.path("{.spec.template.spec.containers[*].name}")`, want: `["nginx", "sidecar"]`},
+		{expr: `obj.path(".status.conditions[?(@.type=='Available')].status")`, want: `"True"`},
+		{expr: `obj.path("{.status.missing}")`, want: `None`},
+		{expr: `obj.condition("Progressing").reason`, want: `"ProgressDeadlineExceeded"`},
+		{expr: `obj.condition("Ready")`, want: `None`},
+		{expr: `bool(obj.status)`, want: `True`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			v, _, err := Eval("test", tc.expr, nil, starlark.StringDict{"obj": obj, "hasattr": HasAttr})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestObjectMissingFieldErrors(t *testing.T) {
+	obj, err := newObjectFromString(testDeployment)
+	if err != nil {
+		t.Fatalf("Failed to decode object: %v", err)
+	}
+
+	for _, tc := range []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: `obj.spec.minReadySeconds + 1`, wantErr: "unknown binary op: NoneType + int"},
+		{expr: `obj.spec.strategy.type`, wantErr: "NoneType has no .type field or method"},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, _, err := Eval("test", tc.expr, nil, starlark.StringDict{"obj": obj})
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("Expected error %q but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestObjectFromRuntime(t *testing.T) {
+	replicas := int32(2)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	obj, err := newObjectFromRuntime(d, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v, _, err := Eval("test", `[obj.kind, obj.apiVersion, obj.spec.replicas]`, nil, starlark.StringDict{"obj": obj})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := `["Deployment", "apps/v1", 2]`; v.String() != want {
+		t.Errorf("Expected %v but got %v", want, v.String())
+	}
+}
+
+func TestObjectRedactsSecrets(t *testing.T) {
+	obj, err := newObjectFromString(`{"kind": "Secret", "data": {"password": "aHVudGVyMg=="}}`)
+	if err != nil {
+		t.Fatalf("Failed to decode object: %v", err)
+	}
+	if want := "data:\n  password: <redacted>\nkind: Secret"; obj.String() != want {
+		t.Errorf("Expected %q but got %q", want, obj.String())
+	}
+	v, _, err := Eval("test", `obj.data.password`, nil, starlark.StringDict{"obj": obj})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := `"aHVudGVyMg=="`; v.String() != want {
+		t.Errorf("Expected %v but got %v", want, v.String())
+	}
+}
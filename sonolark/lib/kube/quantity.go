@@ -0,0 +1,140 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	quantityValueAttr      = "value"
+	quantityMilliValueAttr = "milli_value"
+)
+
+// Quantity wraps a resource.Quantity (e.g. "100m" or "1Gi") so that scripts can compare quantities
+// using the usual operators and add or subtract them.
+type Quantity struct {
+	q resource.Quantity
+}
+
+var (
+	_ starlark.Comparable = &Quantity{}
+	_ starlark.HasBinary  = &Quantity{}
+	_ starlark.HasAttrs   = &Quantity{}
+)
+
+// NewQuantity returns a Quantity wrapping q.
+func NewQuantity(q resource.Quantity) *Quantity {
+	return &Quantity{q: q}
+}
+
+// String implements starlark.Value.String.
+func (q *Quantity) String() string { return q.q.String() }
+
+// Type implements starlark.Value.Type.
+func (q *Quantity) Type() string { return "kube.quantity" }
+
+// Freeze implements starlark.Value.Freeze. Quantities are immutable so this is a no-op.
+func (q *Quantity) Freeze() {}
+
+// Truth implements starlark.Value.Truth.
+// Returns true if the quantity is non-zero.
+func (q *Quantity) Truth() starlark.Bool { return starlark.Bool(!q.q.IsZero()) }
+
+// Hash implements starlark.Value.Hash so that equal quantities (e.g. "1" and "1000m") hash the same.
+func (q *Quantity) Hash() (uint32, error) {
+	v := q.q.MilliValue()
+	return uint32(v ^ (v >> 32)), nil
+}
+
+// CompareSameType implements starlark.Comparable.
+func (q *Quantity) CompareSameType(op syntax.Token, y starlark.Value, _ int) (bool, error) {
+	cmp := q.q.Cmp(y.(*Quantity).q)
+	switch op {
+	case syntax.EQL:
+		return cmp == 0, nil
+	case syntax.NEQ:
+		return cmp != 0, nil
+	case syntax.LT:
+		return cmp < 0, nil
+	case syntax.LE:
+		return cmp <= 0, nil
+	case syntax.GT:
+		return cmp > 0, nil
+	case syntax.GE:
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unsupported comparison %v for %v", op, q.Type())
+}
+
+// Binary implements starlark.HasBinary for adding and subtracting quantities.
+func (q *Quantity) Binary(op syntax.Token, y starlark.Value, side starlark.Side) (starlark.Value, error) {
+	other, ok := y.(*Quantity)
+	if !ok {
+		return nil, nil
+	}
+	l, r := q.q.DeepCopy(), other.q
+	if side == starlark.Right {
+		l, r = other.q.DeepCopy(), q.q
+	}
+	switch op {
+	case syntax.PLUS:
+		l.Add(r)
+	case syntax.MINUS:
+		l.Sub(r)
+	default:
+		return nil, nil
+	}
+	return NewQuantity(l), nil
+}
+
+// Attr implements starlark.HasAttrs.
+func (q *Quantity) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case quantityValueAttr:
+		return starlark.MakeInt64(q.q.Value()), nil
+	case quantityMilliValueAttr:
+		return starlark.MakeInt64(q.q.MilliValue()), nil
+	}
+	return nil, nil
+}
+
+// AttrNames implements starlark.HasAttrs.
+func (q *Quantity) AttrNames() []string {
+	return []string{quantityMilliValueAttr, quantityValueAttr}
+}
+
+// AsGoValue allows quantities to be used with the ytt libraries (e.g. assert.equals and yaml.encode).
+func (q *Quantity) AsGoValue() (interface{}, error) {
+	return q.q.String(), nil
+}
+
+// newResourceListValue converts the quantities in the list (e.g. resource requests or usage) to a
+// struct of Quantity values with a field per resource, e.g. usage.cpu.
+func newResourceListValue(rl corev1.ResourceList) *starlarkstruct.Struct {
+	fields := starlark.StringDict{}
+	for k, v := range rl {
+		fields[string(k)] = NewQuantity(v)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+}
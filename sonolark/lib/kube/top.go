@@ -0,0 +1,186 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+
+	"github.com/cruise-automation/isopod/pkg/addon"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	metricsAPIPath = "/apis/metrics.k8s.io/v1beta1"
+)
+
+// The subset of the metrics.k8s.io/v1beta1 types used by kube.top.
+type (
+	podMetrics struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Timestamp         metav1.Time        `json:"timestamp"`
+		Window            metav1.Duration    `json:"window"`
+		Containers        []containerMetrics `json:"containers"`
+	}
+	podMetricsList struct {
+		Items []podMetrics `json:"items"`
+	}
+	containerMetrics struct {
+		Name  string              `json:"name"`
+		Usage corev1.ResourceList `json:"usage"`
+	}
+	nodeMetrics struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Timestamp         metav1.Time         `json:"timestamp"`
+		Window            metav1.Duration     `json:"window"`
+		Usage             corev1.ResourceList `json:"usage"`
+	}
+	nodeMetricsList struct {
+		Items []nodeMetrics `json:"items"`
+	}
+)
+
+// kubeTopFn is an entry point for the `kube.top` built-in which returns the current resource usage of
+// pods or nodes from the metrics.k8s.io API, like `kubectl top`. Usage is given as kube.quantity values
+// (e.g. pod.usage.cpu) which can be compared with kube.resource_quantity. If a name is given a single
+// result is returned, otherwise a list.
+func (m *kubePackage) kubeTopFn(t *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var kind, namespace, name string
+	unpacked := []interface{}{
+		"kind", &kind,
+		"namespace?", &namespace,
+		"name?", &name,
+	}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
+		return nil, fmt.Errorf("<%v>: %v", b.Name(), err)
+	}
+
+	ctx := t.Local(addon.GoCtxKey).(context.Context)
+	switch kind {
+	case "pods", "pod":
+		p := path.Join(metricsAPIPath, "pods")
+		if len(namespace) > 0 {
+			p = path.Join(metricsAPIPath, "namespaces", namespace, "pods")
+		}
+		if len(name) > 0 {
+			if len(namespace) == 0 {
+				return nil, fmt.Errorf("<%v>: a namespace is required to get the metrics of pod %q", b.Name(), name)
+			}
+			pm := &podMetrics{}
+			if err := m.getJSON(ctx, path.Join(p, name), pm); err != nil {
+				return nil, fmt.Errorf("<%v>: failed to get pod metrics: %v", b.Name(), err)
+			}
+			return podMetricsValue(pm), nil
+		}
+		list := &podMetricsList{}
+		if err := m.getJSON(ctx, p, list); err != nil {
+			return nil, fmt.Errorf("<%v>: failed to list pod metrics: %v", b.Name(), err)
+		}
+		out := make([]starlark.Value, 0, len(list.Items))
+		for i := range list.Items {
+			out = append(out, podMetricsValue(&list.Items[i]))
+		}
+		return starlark.NewList(out), nil
+
+	case "nodes", "node":
+		p := path.Join(metricsAPIPath, "nodes")
+		if len(name) > 0 {
+			nm := &nodeMetrics{}
+			if err := m.getJSON(ctx, path.Join(p, name), nm); err != nil {
+				return nil, fmt.Errorf("<%v>: failed to get node metrics: %v", b.Name(), err)
+			}
+			return nodeMetricsValue(nm), nil
+		}
+		list := &nodeMetricsList{}
+		if err := m.getJSON(ctx, p, list); err != nil {
+			return nil, fmt.Errorf("<%v>: failed to list node metrics: %v", b.Name(), err)
+		}
+		out := make([]starlark.Value, 0, len(list.Items))
+		for i := range list.Items {
+			out = append(out, nodeMetricsValue(&list.Items[i]))
+		}
+		return starlark.NewList(out), nil
+
+	default:
+		return nil, fmt.Errorf("<%v>: expected one of [ pods | nodes ], got: %q", b.Name(), kind)
+	}
+}
+
+func podMetricsValue(pm *podMetrics) starlark.Value {
+	total := corev1.ResourceList{}
+	containers := make([]starlark.Value, 0, len(pm.Containers))
+	for _, c := range pm.Containers {
+		for k, v := range c.Usage {
+			sum := total[k]
+			sum.Add(v)
+			total[k] = sum
+		}
+		containers = append(containers, starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"name":  starlark.String(c.Name),
+			"usage": newResourceListValue(c.Usage),
+		}))
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"name":       starlark.String(pm.Name),
+		"namespace":  starlark.String(pm.Namespace),
+		"timestamp":  starlark.String(pm.Timestamp.UTC().Format(metav1.RFC3339Micro)),
+		"window":     starlark.String(pm.Window.Duration.String()),
+		"containers": starlark.NewList(containers),
+		"usage":      newResourceListValue(total),
+	})
+}
+
+func nodeMetricsValue(nm *nodeMetrics) starlark.Value {
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"name":      starlark.String(nm.Name),
+		"timestamp": starlark.String(nm.Timestamp.UTC().Format(metav1.RFC3339Micro)),
+		"window":    starlark.String(nm.Window.Duration.String()),
+		"usage":     newResourceListValue(nm.Usage),
+	})
+}
+
+// getJSON decodes the JSON response of a GET to the path on the API server into out.
+func (m *kubePackage) getJSON(ctx context.Context, p string, out interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, m.Master+p, nil)
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("GET to %s", req.URL)
+	resp, err := m.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body (response code: %d): %v", resp.StatusCode, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s (response code: %d)", raw, resp.StatusCode)
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -20,11 +20,10 @@ import (
 
 	"github.com/k14s/starlark-go/starlark"
 	"k8s.io/apimachinery/pkg/api/resource"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-// resourceQuantityFn returns a starlark.Value that represents
+// resourceQuantityFn returns a starlark.Value that represents the parsed quantity string, e.g. "100m" or "1Gi".
 func resourceQuantityFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, _ []starlark.Tuple) (starlark.Value, error) {
 	var v string
 	if err := starlark.UnpackPositionalArgs(b.Name(), args, nil, 1, &v); err != nil {
@@ -36,12 +35,7 @@ func resourceQuantityFn(_ *starlark.Thread, b *starlark.Builtin, args starlark.T
 		return nil, fmt.Errorf("%v: failed to parse quantity string: %v", b.Name(), err)
 	}
 
-	un, err := runtime.DefaultUnstructuredConverter.ToUnstructured(q)
-	if err != nil {
-		return nil, fmt.Errorf("<%v>: failed to convert '%v' to unstructured JSON: %v", b.Name(), q, err)
-	}
-
-	return ValueFromNestedMap(un)
+	return NewQuantity(q), nil
 }
 
 // fromStringFn converts Stalark integer to string *intstr.IntOrString
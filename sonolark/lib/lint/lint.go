@@ -0,0 +1,240 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lint statically checks sonolark scripts. Scripts are parsed and resolved against
+// the predeclared modules but never executed, so problems can be caught before a cluster run.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/k14s/starlark-go/resolve"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+
+	startTestFn = "sonobuoy.startTest"
+	passTestFn  = "sonobuoy.passTest"
+	failTestFn  = "sonobuoy.failTest"
+)
+
+var (
+	// dynamicModules have members which are only known at runtime (e.g. env.* comes from
+	// SONOLARK_ env vars) so unknown members are not reported.
+	dynamicModules = map[string]bool{"env": true}
+)
+
+// Issue is a single problem found in a script.
+type Issue struct {
+	Line     int32  `json:"line"`
+	Column   int32  `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Result is the outcome of checking a single script.
+type Result struct {
+	File   string  `json:"file"`
+	Issues []Issue `json:"issues"`
+}
+
+// HasErrors returns true if any of the issues found are errors rather than warnings.
+func (r *Result) HasErrors() bool {
+	for _, i := range r.Issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Result) add(pos syntax.Position, severity, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{
+		Line:     pos.Line,
+		Column:   pos.Col,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Check parses and resolves the script without executing it. Undefined names, unknown module
+// members and calls to known builtins with the wrong arguments are reported as errors. Tests
+// which are started but never passed or failed are reported as warnings. The src argument
+// is handled the same as starlark.ExecFile; if nil the file is read from disk.
+func Check(filename string, src interface{}, predeclared starlark.StringDict) (*Result, error) {
+	result := &Result{File: filename, Issues: []Issue{}}
+
+	f, err := syntax.Parse(filename, src, 0)
+	if err != nil {
+		if synErr, ok := err.(syntax.Error); ok {
+			result.add(synErr.Pos, SeverityError, "%v", synErr.Msg)
+			return result, nil
+		}
+		return nil, err
+	}
+
+	if err := resolve.File(f, predeclared.Has, starlark.Universe.Has); err != nil {
+		errList, ok := err.(resolve.ErrorList)
+		if !ok {
+			return nil, err
+		}
+		for _, e := range errList {
+			result.add(e.Pos, SeverityError, "%v", e.Msg)
+		}
+	}
+
+	syntax.Walk(f, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case *syntax.DotExpr:
+			checkMember(result, n, predeclared)
+		case *syntax.CallExpr:
+			checkCall(result, n)
+		}
+		return true
+	})
+
+	checkTestLifecycle(result, f)
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].Line != result.Issues[j].Line {
+			return result.Issues[i].Line < result.Issues[j].Line
+		}
+		return result.Issues[i].Column < result.Issues[j].Column
+	})
+	return result, nil
+}
+
+// predeclaredIdent returns the identifier if e refers to a predeclared value.
+func predeclaredIdent(e syntax.Expr) (*syntax.Ident, bool) {
+	id, ok := e.(*syntax.Ident)
+	if !ok {
+		return nil, false
+	}
+	b, ok := id.Binding.(*resolve.Binding)
+	if !ok || b.Scope != resolve.Predeclared {
+		return nil, false
+	}
+	return id, true
+}
+
+// qualifiedName returns names like "kube.get" when fn is a member of a predeclared module.
+func qualifiedName(fn syntax.Expr) string {
+	dot, ok := fn.(*syntax.DotExpr)
+	if !ok {
+		return ""
+	}
+	id, ok := predeclaredIdent(dot.X)
+	if !ok {
+		return ""
+	}
+	return id.Name + "." + dot.Name.Name
+}
+
+func checkMember(result *Result, dot *syntax.DotExpr, predeclared starlark.StringDict) {
+	id, ok := predeclaredIdent(dot.X)
+	if !ok || dynamicModules[id.Name] {
+		return
+	}
+	mod, ok := predeclared[id.Name].(starlark.HasAttrs)
+	if !ok {
+		return
+	}
+	for _, name := range mod.AttrNames() {
+		if name == dot.Name.Name {
+			return
+		}
+	}
+	result.add(dot.Name.NamePos, SeverityError, "%v has no member %q", id.Name, dot.Name.Name)
+}
+
+func checkCall(result *Result, call *syntax.CallExpr) {
+	name := qualifiedName(call.Fn)
+	sig, ok := Signatures[name]
+	if !ok {
+		return
+	}
+
+	nargs := 0
+	kwargs := []string{}
+	for _, arg := range call.Args {
+		switch arg := arg.(type) {
+		case *syntax.UnaryExpr:
+			// Calls using *args or **kwargs can't be checked statically.
+			if arg.Op == syntax.STAR || arg.Op == syntax.STARSTAR {
+				return
+			}
+			nargs++
+		case *syntax.BinaryExpr:
+			if arg.Op == syntax.EQ {
+				kwargs = append(kwargs, arg.X.(*syntax.Ident).Name)
+				continue
+			}
+			nargs++
+		default:
+			nargs++
+		}
+	}
+
+	if msg := sig.check(name, nargs, kwargs); msg != "" {
+		result.add(call.Lparen, SeverityError, "%v", msg)
+	}
+}
+
+// checkTestLifecycle warns about tests which are started but not passed or failed within
+// the same function (or top-level code) before another test is started or the function ends.
+// Such tests get marked as failed by sonobuoy.done or are silently dropped.
+func checkTestLifecycle(result *Result, f *syntax.File) {
+	var toplevel []syntax.Stmt
+	for _, stmt := range f.Stmts {
+		if def, ok := stmt.(*syntax.DefStmt); ok {
+			checkTestLifecycleInBlock(result, def.Body)
+			continue
+		}
+		toplevel = append(toplevel, stmt)
+	}
+	checkTestLifecycleInBlock(result, toplevel)
+}
+
+func checkTestLifecycleInBlock(result *Result, stmts []syntax.Stmt) {
+	var open *syntax.CallExpr
+	for _, stmt := range stmts {
+		syntax.Walk(stmt, func(n syntax.Node) bool {
+			switch n := n.(type) {
+			case *syntax.DefStmt, *syntax.LambdaExpr:
+				return false
+			case *syntax.CallExpr:
+				switch qualifiedName(n.Fn) {
+				case startTestFn:
+					if open != nil {
+						result.add(open.Lparen, SeverityWarning, "test started here is never passed or failed before the next call to %v", startTestFn)
+					}
+					open = n
+				case passTestFn, failTestFn:
+					open = nil
+				}
+			}
+			return true
+		})
+	}
+	if open != nil {
+		result.add(open.Lparen, SeverityWarning, "test started here is never passed or failed")
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+)
+
+func noop(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return starlark.None, nil
+}
+
+func testPredeclared() starlark.StringDict {
+	module := func(name string, members ...string) *starlarkstruct.Module {
+		m := &starlarkstruct.Module{Name: name, Members: starlark.StringDict{}}
+		for _, member := range members {
+			m.Members[member] = starlark.NewBuiltin(name+"."+member, noop)
+		}
+		return m
+	}
+	return starlark.StringDict{
+		"sonobuoy": module("sonobuoy", "startSuite", "startTest", "passTest", "failTest", "done"),
+		"kube":     module("kube", "get", "exists", "put"),
+		"assert":   module("assert", "equals", "fail"),
+		"env":      module("env"),
+		"log":      module("log", "info"),
+	}
+}
+
+func TestCheck(t *testing.T) {
+	testcases := []struct {
+		desc   string
+		src    string
+		expect []string
+	}{
+		{
+			desc: "Valid script",
+			src: `
+def foo():
+  sonobuoy.startTest("a")
+  assert.equals(1, 1, "msg")
+  kube.get(pod="ns/name", wait="1s")
+  kube.put(name="a", data=[])
+  sonobuoy.passTest()
+
+foo()
+print(env.anything)
+log.info("done", pods=3, ns="default")
+`,
+		}, {
+			desc:   "Syntax error",
+			src:    "def foo(:\n",
+			expect: []string{"1:10: error: got ':', want ')'"},
+		}, {
+			desc:   "Undefined name",
+			src:    "foo(1)\n",
+			expect: []string{"1:1: error: undefined: foo"},
+		}, {
+			desc:   "Unknown module member",
+			src:    "kube.gett(pod='x')\n",
+			expect: []string{"1:6: error: kube has no member \"gett\""},
+		}, {
+			desc: "Wrong arity for positional builtins",
+			src:  "assert.equals(1)\nsonobuoy.failTest(msg='x')\n",
+			expect: []string{
+				"1:14: error: assert.equals expects 2 to 3 arguments, got 1",
+				"2:18: error: sonobuoy.failTest does not accept keyword arguments, got msg",
+			},
+		}, {
+			desc: "Wrong arguments for resource builtins",
+			src:  "kube.get('pod')\nkube.get(pod='a', bogus=1)\nkube.exists()\n",
+			expect: []string{
+				"1:9: error: kube.get does not accept positional arguments",
+				"2:9: error: kube.get expects one of [ api_group | wait | json | object ] after <resource>=<name>, got \"bogus\"",
+				"3:12: error: kube.exists expects <resource>=<name>",
+			},
+		}, {
+			desc: "Wrong arguments for param builtins",
+			src:  "kube.put(name='a')\nkube.put('a', [], name='b')\n",
+			expect: []string{
+				"1:9: error: kube.put is missing argument \"data\"",
+				"2:9: error: kube.put got multiple values for argument \"name\"",
+			},
+		}, {
+			desc:   "Wrong arguments for field builtins",
+			src:    "log.info(pods=3)\n",
+			expect: []string{"1:9: error: log.info expects 1 argument, got 0"},
+		}, {
+			desc:   "Star args are not checked",
+			src:    "args = [1]\nassert.equals(*args)\n",
+			expect: nil,
+		}, {
+			desc: "Test never completed",
+			src: `
+def foo():
+  sonobuoy.startTest("a")
+  sonobuoy.startTest("b")
+
+sonobuoy.startTest("c")
+sonobuoy.failTest("c")
+`,
+			expect: []string{
+				"3:21: warning: test started here is never passed or failed before the next call to sonobuoy.startTest",
+				"4:21: warning: test started here is never passed or failed",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := Check("test.star", tc.src, testPredeclared())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, i := range result.Issues {
+				got = append(got, fmt.Sprintf("%v:%v: %v: %v", i.Line, i.Column, i.Severity, i.Message))
+			}
+			if strings.Join(got, "\n") != strings.Join(tc.expect, "\n") {
+				t.Errorf("Expected issues:\n%v\nbut got:\n%v", strings.Join(tc.expect, "\n"), strings.Join(got, "\n"))
+			}
+			if result.HasErrors() != strings.Contains(strings.Join(tc.expect, "\n"), "error:") {
+				t.Errorf("Unexpected HasErrors value %v", result.HasErrors())
+			}
+		})
+	}
+}
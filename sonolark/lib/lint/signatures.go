@@ -0,0 +1,236 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+type signatureKind int
+
+const (
+	// kindPositional builtins only accept positional arguments.
+	kindPositional signatureKind = iota
+	// kindParams builtins accept named parameters either positionally or as keywords,
+	// the same as starlark.UnpackArgs.
+	kindParams
+	// kindResource builtins take a leading <resource>=<name> keyword followed by
+	// optional keywords, like kube.get.
+	kindResource
+	// kindFields builtins take positional arguments followed by any keyword arguments,
+	// like log.info(msg, **fields).
+	kindFields
+)
+
+// Signature describes the arguments a builtin accepts so calls can be checked without
+// executing them.
+type Signature struct {
+	kind    signatureKind
+	min     int
+	max     int
+	params  []string
+	options []string
+}
+
+// Positional returns a signature for a builtin taking between min and max positional arguments.
+func Positional(min, max int) Signature {
+	return Signature{kind: kindPositional, min: min, max: max}
+}
+
+// Params returns a signature for a builtin unpacked via starlark.UnpackArgs. Optional
+// parameters are suffixed with "?".
+func Params(params ...string) Signature {
+	return Signature{kind: kindParams, params: params}
+}
+
+// Resource returns a signature for a builtin called like kube.get(<resource>=<name>, ...).
+func Resource(options ...string) Signature {
+	return Signature{kind: kindResource, options: options}
+}
+
+// Fields returns a signature for a builtin taking between min and max positional arguments
+// and any keyword arguments.
+func Fields(min, max int) Signature {
+	return Signature{kind: kindFields, min: min, max: max}
+}
+
+// Signatures holds the known arity of the builtins predeclared by sonolark, keyed by
+// their fully qualified name.
+var Signatures = map[string]Signature{
+	"sonobuoy.startSuite": Positional(0, 1),
+	"sonobuoy.startTest":  Positional(1, 1),
+	"sonobuoy.passTest":   Positional(0, 1),
+	"sonobuoy.failTest":   Positional(1, 1),
+	"sonobuoy.done":       Positional(0, 0),
+
+	"assert.equals":     Positional(2, 3),
+	"assert.fail":       Positional(1, 1),
+	"assert.not_equals": Positional(2, 3),
+	"assert.contains":   Positional(2, 3),
+	"assert.matches":    Positional(2, 3),
+	"assert.greater":    Positional(2, 3),
+	"assert.less":       Positional(2, 3),
+	"assert.approx":     Params("expected", "actual", "tolerance", "msg?"),
+	"assert.len":        Positional(2, 3),
+	"assert.is_none":    Positional(1, 2),
+	"assert.subset_of":  Positional(2, 3),
+	"assert.all":        Positional(1, 2),
+	"assert.any":        Positional(1, 2),
+	"assert.eventually": Params("fn", "timeout", "interval?", "msg?"),
+
+	"parallel.map": Params("fn", "items", "concurrency?"),
+
+	"kube.get":               Resource("api_group", "wait", "json", "object"),
+	"kube.exists":            Resource("api_group", "wait"),
+	"kube.delete":            Resource("api_group", "foreground"),
+	"kube.put":               Params("name", "data", "namespace?"),
+	"kube.diff":              Positional(2, 2),
+	"kube.portforward":       Params("namespace", "pod", "local_port", "pod_port", "test_path?", "fn?"),
+	"kube.resource_quantity": Positional(1, 1),
+	"kube.from_str":          Positional(1, 1),
+	"kube.from_int":          Positional(1, 1),
+	"kube.object":            Positional(1, 1),
+	"kube.drift":             Params("manifests", "ignore?"),
+	"kube.as_user":           Params("user", "groups?"),
+	"kube.can_i":             Params("verb", "resource", "namespace?", "name?", "api_group?"),
+	"kube.context":           Positional(1, 1),
+	"kube.events":            Params("involved_object?", "namespace?", "since?", "type?"),
+	"kube.top":               Params("kind", "namespace?", "name?"),
+
+	"fs.read": Params("path"),
+	"fs.glob": Params("pattern"),
+	"fs.stat": Params("path"),
+
+	"time.now":      Params(),
+	"time.parse":    Params("value", "format?"),
+	"time.duration": Params("value"),
+
+	"exec.run": Params("cmd", "timeout?"),
+
+	"tls.parse_pem": Params("data"),
+	"tls.dial":      Params("addr", "sni?", "timeout?"),
+	"tls.verify":    Params("chain", "roots?", "dns_name?", "at?"),
+
+	"log.debug": Fields(1, 1),
+	"log.info":  Fields(1, 1),
+	"log.warn":  Fields(1, 1),
+
+	"params.declare":  Fields(0, 0),
+	"params.string":   Params("default?", "required?", "doc?"),
+	"params.int":      Params("default?", "required?", "doc?"),
+	"params.bool":     Params("default?", "required?", "doc?"),
+	"params.duration": Params("default?", "required?", "doc?"),
+	"params.dict":     Params("default?", "required?", "doc?"),
+	"params.any":      Params("default?", "required?", "doc?"),
+	"params.list":     Params("item?", "default?", "required?", "doc?"),
+
+	"prom.query":       Params("url", "expr", "time?", "timeout?", "headers?"),
+	"prom.query_range": Params("url", "expr", "start", "stop", "step", "timeout?", "headers?"),
+}
+
+func init() {
+	// The check module provides soft versions of each assertion with the same arguments.
+	for name, sig := range Signatures {
+		if strings.HasPrefix(name, "assert.") {
+			Signatures["check."+strings.TrimPrefix(name, "assert.")] = sig
+		}
+	}
+}
+
+// check returns a description of what is wrong with a call to the builtin name given the
+// number of positional arguments and the names of the keyword arguments. It returns the
+// empty string if the call looks valid.
+func (s Signature) check(name string, nargs int, kwargs []string) string {
+	switch s.kind {
+	case kindPositional:
+		if len(kwargs) > 0 {
+			return fmt.Sprintf("%v does not accept keyword arguments, got %v", name, strings.Join(kwargs, ", "))
+		}
+		if nargs < s.min || nargs > s.max {
+			return fmt.Sprintf("%v expects %v, got %v", name, describeRange(s.min, s.max), nargs)
+		}
+	case kindParams:
+		if nargs > len(s.params) {
+			return fmt.Sprintf("%v expects at most %v arguments, got %v", name, len(s.params), nargs)
+		}
+		given := map[string]bool{}
+		for _, p := range s.params[:nargs] {
+			given[strings.TrimSuffix(p, "?")] = true
+		}
+		for _, kw := range kwargs {
+			if !s.hasParam(kw) {
+				return fmt.Sprintf("%v got an unexpected keyword argument %q", name, kw)
+			}
+			if given[kw] {
+				return fmt.Sprintf("%v got multiple values for argument %q", name, kw)
+			}
+			given[kw] = true
+		}
+		for _, p := range s.params {
+			if !strings.HasSuffix(p, "?") && !given[p] {
+				return fmt.Sprintf("%v is missing argument %q", name, p)
+			}
+		}
+	case kindFields:
+		if nargs < s.min || nargs > s.max {
+			return fmt.Sprintf("%v expects %v, got %v", name, describeRange(s.min, s.max), nargs)
+		}
+	case kindResource:
+		if nargs > 0 {
+			return fmt.Sprintf("%v does not accept positional arguments", name)
+		}
+		if len(kwargs) == 0 {
+			return fmt.Sprintf("%v expects <resource>=<name>", name)
+		}
+		for _, kw := range kwargs[1:] {
+			if !contains(s.options, kw) {
+				return fmt.Sprintf("%v expects one of [ %v ] after <resource>=<name>, got %q", name, strings.Join(s.options, " | "), kw)
+			}
+		}
+	}
+	return ""
+}
+
+func (s Signature) hasParam(name string) bool {
+	for _, p := range s.params {
+		if strings.TrimSuffix(p, "?") == name {
+			return true
+		}
+	}
+	return false
+}
+
+func describeRange(min, max int) string {
+	switch {
+	case min == max && min == 1:
+		return "1 argument"
+	case min == max:
+		return fmt.Sprintf("%v arguments", min)
+	default:
+		return fmt.Sprintf("%v to %v arguments", min, max)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
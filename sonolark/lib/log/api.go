@@ -0,0 +1,73 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"fmt"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+var (
+	API = starlark.StringDict{
+		"log": &starlarkstruct.Module{
+			Name: "log",
+			Members: starlark.StringDict{
+				"debug": starlark.NewBuiltin("log.debug", logFn(logrus.DebugLevel)),
+				"info":  starlark.NewBuiltin("log.info", logFn(logrus.InfoLevel)),
+				"warn":  starlark.NewBuiltin("log.warn", logFn(logrus.WarnLevel)),
+			},
+		},
+	}
+)
+
+// logFn returns a builtin which logs the message at the level via logrus, along with the keyword
+// arguments as fields, the position in the script and the test currently running (if any).
+func logFn(level logrus.Level) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if args.Len() != 1 {
+			return starlark.None, fmt.Errorf("%v: expected exactly one positional argument (the message), got %v", b.Name(), args.Len())
+		}
+		msg, ok := starlark.AsString(args.Index(0))
+		if !ok {
+			msg = args.Index(0).String()
+		}
+
+		fields := logrus.Fields{}
+		for _, kv := range kwargs {
+			k, v := string(kv[0].(starlark.String)), kv[1]
+			if goVal, err := core.NewStarlarkValue(v).AsGoValue(); err == nil {
+				fields[k] = goVal
+			} else {
+				fields[k] = v.String()
+			}
+		}
+		if pos := thread.CallFrame(1).Pos; pos.IsValid() {
+			fields["pos"] = pos.String()
+		}
+		if testName := sonobuoy.CurrentTest(thread); len(testName) > 0 {
+			fields["test"] = testName
+		}
+
+		logrus.WithFields(fields).Log(level, msg)
+		return starlark.None, nil
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+func TestLogAPI(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tc := range []struct {
+		desc       string
+		src        string
+		test       string
+		wantLevel  logrus.Level
+		wantMsg    string
+		wantFields logrus.Fields
+		wantErr    string
+	}{
+		{
+			desc:       "Info with fields",
+			src:        `log.info("checked pods", count=3, names=["a", "b"])`,
+			wantLevel:  logrus.InfoLevel,
+			wantMsg:    "checked pods",
+			wantFields: logrus.Fields{"count": int64(3), "names": []interface{}{"a", "b"}, "pos": "test.star:1:9"},
+		}, {
+			desc:       "Warn includes the running test",
+			src:        `log.warn("slow")`,
+			test:       "pods are ready",
+			wantLevel:  logrus.WarnLevel,
+			wantMsg:    "slow",
+			wantFields: logrus.Fields{"pos": "test.star:1:9", "test": "pods are ready"},
+		}, {
+			desc:       "Debug",
+			src:        "def f():\n  log.debug(1)\nf()",
+			wantLevel:  logrus.DebugLevel,
+			wantMsg:    "1",
+			wantFields: logrus.Fields{"pos": "test.star:2:12"},
+		}, {
+			desc:    "Missing message",
+			src:     `log.info(a=1)`,
+			wantErr: "log.info: expected exactly one positional argument (the message), got 0",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			hook.Reset()
+			thread := &starlark.Thread{Name: t.Name()}
+			shared.SetGoCtx(thread, context.WithValue(context.Background(), sonobuoy.CurrentTestCtxKey, tc.test))
+			_, err := starlark.ExecFile(thread, "test.star", tc.src, API)
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+
+			e := hook.LastEntry()
+			if e == nil {
+				t.Fatalf("Expected a log entry but got none")
+			}
+			if e.Level != tc.wantLevel || e.Message != tc.wantMsg {
+				t.Errorf("Expected %v %q but got %v %q", tc.wantLevel, tc.wantMsg, e.Level, e.Message)
+			}
+			if diff := cmp.Diff(tc.wantFields, e.Data); diff != "" {
+				t.Errorf("Unexpected fields (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAPITrace(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/pods" {
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	var buf bytes.Buffer
+	client := &http.Client{Transport: NewAPITrace(&buf).Wrap(http.DefaultTransport)}
+
+	ctx := context.WithValue(context.Background(), sonobuoy.CurrentTestCtxKey, "list pods")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/api/v1/pods?limit=1", nil)
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodDelete, s.URL+"/api/v1/namespaces/a", nil)
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "http://127.0.0.1:1/healthz", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("Expected an error connecting to a closed port")
+	}
+
+	var got []APITraceEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		e := APITraceEntry{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Failed to parse trace line %q: %v", line, err)
+		}
+		if e.Time.IsZero() || e.LatencyMS < 0 {
+			t.Errorf("Expected a time and latency but got %+v", e)
+		}
+		// Clear the fields which vary between runs.
+		e.Time, e.LatencyMS, e.Host = time.Time{}, 0, ""
+		got = append(got, e)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 trace entries but got %v", len(got))
+	}
+	if got[2].Error == "" {
+		t.Errorf("Expected the failed request to record an error but got %+v", got[2])
+	}
+	got[2].Error = ""
+
+	want := []APITraceEntry{
+		{Method: "GET", Path: "/api/v1/pods", Query: "limit=1", Status: 200, Test: "list pods"},
+		{Method: "DELETE", Path: "/api/v1/namespaces/a", Status: 404},
+		{Method: "GET", Path: "/healthz"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected trace (-want +got):\n%s", diff)
+	}
+}
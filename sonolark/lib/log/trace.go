@@ -0,0 +1,106 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+const (
+	// APITraceFileName is the name of the file in the results directory which API requests are traced to.
+	APITraceFileName = "api-trace.jsonl"
+)
+
+// APITrace records each HTTP request sent by a client to the writer as a line of JSON.
+type APITrace struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// APITraceEntry is a single request recorded by an APITrace.
+type APITraceEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Host      string    `json:"host"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Test      string    `json:"test,omitempty"`
+}
+
+// NewAPITrace returns an APITrace which writes to w.
+func NewAPITrace(w io.Writer) *APITrace {
+	return &APITrace{w: w}
+}
+
+// Wrap returns a RoundTripper which records each request before passing on the response. It has the
+// signature of a transport.WrapperFunc so it can be applied to a rest.Config.
+func (a *APITrace) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &tracingRoundTripper{trace: a, next: rt}
+}
+
+func (a *APITrace) record(e APITraceEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		logrus.Errorf("Failed to encode API trace entry: %v", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(b, '\n')); err != nil {
+		logrus.Errorf("Failed to write API trace entry: %v", err)
+	}
+}
+
+type tracingRoundTripper struct {
+	trace *APITrace
+	next  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper. The latency is the time until the response headers are
+// received. Requests made while a test is running are labelled with the test name.
+func (t *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	e := APITraceEntry{
+		Time:      start.UTC(),
+		Method:    req.Method,
+		Host:      req.URL.Host,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	e.Test, _ = req.Context().Value(sonobuoy.CurrentTestCtxKey).(string)
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Status = resp.StatusCode
+	}
+	t.trace.record(e)
+	return resp, err
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parallel
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+const (
+	// DefaultConcurrency is the number of threads used by parallel.map if not specified.
+	DefaultConcurrency = 10
+)
+
+var (
+	API = starlark.StringDict{
+		"parallel": &starlarkstruct.Module{
+			Name: "parallel",
+			Members: starlark.StringDict{
+				"map": starlark.NewBuiltin("parallel.map", Map),
+			},
+		},
+	}
+)
+
+// Map calls fn(item) for each of the items, each in its own thread, and returns a list of the results
+// in the same order as the items. At most `concurrency` threads run at once. Each thread has its own
+// Go context and test state so it may start and complete its own tests. If any of the calls fail,
+// the error from the first failed item is returned once all the calls have completed.
+//
+// Each call receives its own copy of its item so the caller's values remain mutable. Only fn is
+// frozen, so the calling script may keep changing its globals once Map returns.
+func Map(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fn starlark.Callable
+	var iterable starlark.Iterable
+	concurrency := DefaultConcurrency
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "fn", &fn, "items", &iterable, "concurrency?", &concurrency); err != nil {
+		return starlark.None, err
+	}
+	if concurrency < 1 {
+		return starlark.None, fmt.Errorf("%v: concurrency must be at least 1, got %v", b.Name(), concurrency)
+	}
+
+	var items []starlark.Value
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		items = append(items, item)
+	}
+
+	// fn is shared by every thread so it must be frozen, while each thread gets its own copy of its item.
+	fn.Freeze()
+	for i, item := range items {
+		c, err := copyValue(item)
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: item %v: %w", b.Name(), i, err)
+		}
+		items[i] = c
+	}
+
+	results := make([]starlark.Value, len(items))
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem }()
+			defer wg.Done()
+			results[i], errs[i] = call(thread, fn, i, items[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: item %v: %w", b.Name(), i, err)
+		}
+	}
+	return starlark.NewList(results), nil
+}
+
+// call invokes fn(item) in a new thread. Any test left running by fn is failed so that results
+// are not attributed to the wrong test.
+func call(parent *starlark.Thread, fn starlark.Callable, i int, item starlark.Value) (starlark.Value, error) {
+	child := shared.NewChildThread(parent, fmt.Sprintf("%v[%v]", fn.Name(), i))
+	shared.SetGoCtxWithValues(child, sonobuoy.CurrentTestCtxKey, "")
+
+	v, err := starlark.Call(child, fn, starlark.Tuple{item}, nil)
+	if err != nil {
+		msg := err.Error()
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			msg = evalErr.Backtrace()
+		}
+		sonobuoy.FailRunningTest(child, msg)
+		return nil, err
+	}
+
+	sonobuoy.FailRunningTest(child, "parallel call completed while test still running")
+	return v, nil
+}
+
+// copyValue returns a deep copy of lists, dicts, sets, tuples and structs so that each thread gets
+// values that no other thread can reach. Other values are returned as is.
+func copyValue(v starlark.Value) (starlark.Value, error) {
+	switch v := v.(type) {
+	case *starlark.List:
+		elems := make([]starlark.Value, v.Len())
+		for i := range elems {
+			c, err := copyValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = c
+		}
+		return starlark.NewList(elems), nil
+	case starlark.Tuple:
+		out := make(starlark.Tuple, len(v))
+		for i, elem := range v {
+			c, err := copyValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	case *starlark.Dict:
+		out := starlark.NewDict(v.Len())
+		for _, kv := range v.Items() {
+			c, err := copyValue(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			if err := out.SetKey(kv[0], c); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case *starlark.Set:
+		out := starlark.NewSet(v.Len())
+		iter := v.Iterate()
+		defer iter.Done()
+		var elem starlark.Value
+		for iter.Next(&elem) {
+			if err := out.Insert(elem); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case *starlarkstruct.Struct:
+		fields := starlark.StringDict{}
+		v.ToStringDict(fields)
+		for k, field := range fields {
+			c, err := copyValue(field)
+			if err != nil {
+				return nil, err
+			}
+			fields[k] = c
+		}
+		return starlarkstruct.FromStringDict(v.Constructor(), fields), nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package parallel
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	sono "github.com/vmware-tanzu/sonobuoy-plugins/plugin-helper"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/sonobuoy"
+)
+
+func TestMap(t *testing.T) {
+	testcases := []struct {
+		desc        string
+		src         string
+		expectErr   string
+		expectTests []string
+	}{
+		{
+			desc: "Results are returned in order",
+			src: `
+def double(x):
+  return x * 2
+
+def run():
+  r = parallel.map(double, range(20), concurrency=3)
+  if r != [x * 2 for x in range(20)]:
+    fail("unexpected result: %s" % r)
+
+run()
+`,
+		}, {
+			desc: "Each thread reports its own tests",
+			src: `
+def check(ns):
+  sonobuoy.startTest("check " + ns)
+  if ns == "b":
+    sonobuoy.failTest("bad namespace")
+    return
+  sonobuoy.passTest()
+
+parallel.map(check, ["a", "b", "c"])
+`,
+			expectTests: []string{"check a=passed", "check b=failed", "check c=passed"},
+		}, {
+			desc: "Errors fail the running test and are returned",
+			src: `
+def check(ns):
+  sonobuoy.startTest("check " + ns)
+  if ns == "b":
+    fail("oops")
+  sonobuoy.passTest()
+
+parallel.map(check, ["a", "b"])
+`,
+			expectErr:   "parallel.map: item 1: fail: oops",
+			expectTests: []string{"check a=passed", "check b=failed"},
+		}, {
+			desc: "Tests left running are failed",
+			src: `
+def check(ns):
+  sonobuoy.startTest("check " + ns)
+
+parallel.map(check, ["a"])
+`,
+			expectTests: []string{"check a=failed"},
+		}, {
+			desc: "Items are copied so the caller's values remain mutable",
+			src: `
+def append(l):
+  l.append("x")
+  return len(l)
+
+def run():
+  items = [["a"], ["b", "c"]]
+  r = parallel.map(append, items)
+  if r != [2, 3]:
+    fail("unexpected result: %s" % r)
+  if items != [["a"], ["b", "c"]]:
+    fail("items were modified: %s" % items)
+  items.append(["d"])
+  items[0].append("e")
+
+run()
+`,
+		}, {
+			desc: "Module globals remain mutable once map returns",
+			src: `
+results = []
+
+def upper(x):
+  return x.upper()
+
+def check():
+  if results != ["A", "B", "c"]:
+    fail("unexpected results: %s" % results)
+
+results.extend(parallel.map(upper, ["a", "b"]))
+results.append("c")
+check()
+`,
+		}, {
+			desc:      "Invalid concurrency",
+			src:       `parallel.map(str, ["a"], concurrency=0)`,
+			expectErr: "parallel.map: concurrency must be at least 1, got 0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			thread := &starlark.Thread{}
+			shared.SetGoCtx(thread, context.Background())
+			sonobuoy.StartSuite(thread, -1)
+
+			predeclared := starlark.StringDict{
+				"parallel": API["parallel"],
+				"sonobuoy": sonobuoy.API["sonobuoy"],
+			}
+			_, err := starlark.ExecFile(thread, "test.star", tc.src, predeclared)
+			switch {
+			case err != nil && len(tc.expectErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.expectErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.expectErr)
+			case err != nil && !strings.Contains(err.Error(), tc.expectErr):
+				t.Fatalf("Expected error %q but got %q", tc.expectErr, err.Error())
+			}
+
+			w := shared.GetGoCtx(thread).Value(sonobuoy.WriterCtxKey).(*sono.SonobuoyResultsWriter)
+			got := []string{}
+			for _, item := range w.Data.Items {
+				got = append(got, item.Name+"="+item.Status)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.expectTests, ",") {
+				t.Errorf("Expected tests %v but got %v", tc.expectTests, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package params provides the params module which gives scripts typed, validated access to values
+// supplied by the user in a YAML or JSON file, so that one script can be used with different settings.
+package params
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultValuesFile is the name of the values file looked for in the Sonobuoy config directory.
+	DefaultValuesFile = "values.yaml"
+
+	typeString   = "string"
+	typeInt      = "int"
+	typeBool     = "bool"
+	typeDuration = "duration"
+	typeList     = "list"
+	typeDict     = "dict"
+	typeAny      = "any"
+)
+
+// NewAPI returns the "params" module with the given values, as loaded by LoadValues.
+func NewAPI(values map[string]interface{}) starlark.StringDict {
+	m := &paramsModule{values: values}
+	members := starlark.StringDict{
+		"declare": starlark.NewBuiltin("params.declare", m.declare),
+		"list":    starlark.NewBuiltin("params.list", newListParam),
+	}
+	for _, typ := range []string{typeString, typeInt, typeBool, typeDuration, typeDict, typeAny} {
+		members[typ] = starlark.NewBuiltin("params."+typ, newParamFn(typ))
+	}
+	return starlark.StringDict{
+		"params": &starlarkstruct.Module{Name: "params", Members: members},
+	}
+}
+
+// LoadValues reads the values from a YAML or JSON file. Numbers are kept as json.Number so that
+// integers and floats can be told apart.
+func LoadValues(path string) (map[string]interface{}, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse values file %v: %v", path, err)
+	}
+
+	values := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.UseNumber()
+	if err := d.Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %v: expected a map of parameter names to values: %v", path, err)
+	}
+	return values, nil
+}
+
+// LoadValuesIfExists is like LoadValues but returns no values rather than an error if the file does
+// not exist.
+func LoadValuesIfExists(path string) (map[string]interface{}, error) {
+	values, err := LoadValues(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	return values, err
+}
+
+// Param describes a parameter declared by a script: its type, default and whether it must be set.
+type Param struct {
+	typ      string
+	def      starlark.Value
+	required bool
+	doc      string
+	item     *Param
+}
+
+// String implements starlark.Value.String.
+func (p *Param) String() string {
+	switch {
+	case p.item != nil:
+		return fmt.Sprintf("params.list(item = %v)", p.item)
+	case p.required:
+		return fmt.Sprintf("params.%v(required = True)", p.typ)
+	case p.def != starlark.None:
+		return fmt.Sprintf("params.%v(default = %v)", p.typ, p.def)
+	}
+	return fmt.Sprintf("params.%v()", p.typ)
+}
+
+// Type implements starlark.Value.Type.
+func (p *Param) Type() string { return "params.param" }
+
+// Freeze implements starlark.Value.Freeze.
+func (p *Param) Freeze() { p.def.Freeze() }
+
+// Truth implements starlark.Value.Truth.
+func (p *Param) Truth() starlark.Bool { return starlark.True }
+
+// Hash implements starlark.Value.Hash.
+func (p *Param) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", p.Type()) }
+
+func newParamFn(typ string) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		p := &Param{typ: typ, def: starlark.None}
+		if err := starlark.UnpackArgs(b.Name(), args, kwargs, "default?", &p.def, "required?", &p.required, "doc?", &p.doc); err != nil {
+			return starlark.None, err
+		}
+		return p, p.checkDeclaration(b.Name())
+	}
+}
+
+func newListParam(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	p := &Param{typ: typeList, def: starlark.None, item: &Param{typ: typeAny, def: starlark.None}}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "item?", &p.item, "default?", &p.def, "required?", &p.required, "doc?", &p.doc); err != nil {
+		return starlark.None, err
+	}
+	return p, p.checkDeclaration(b.Name())
+}
+
+func (p *Param) checkDeclaration(name string) error {
+	if p.required && p.def != starlark.None {
+		return fmt.Errorf("%v: a required parameter can't have a default", name)
+	}
+	if p.typ == typeDuration && p.def != starlark.None {
+		d, err := host.ToDuration(p.def)
+		if err != nil {
+			return fmt.Errorf("%v: invalid default: %v", name, err)
+		}
+		p.def = host.Duration(d)
+	}
+	return nil
+}
+
+type paramsModule struct {
+	values map[string]interface{}
+}
+
+// declare validates the values against the parameters given as keyword arguments, e.g.
+// params.declare(namespace=params.string(required=True), max_restarts=params.int(default=3)), and
+// returns a struct with a field for each. Every problem is reported at once, as are values for
+// parameters which were not declared since they are usually typos.
+func (m *paramsModule) declare(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() > 0 {
+		return starlark.None, fmt.Errorf("%v: parameters must be given as keyword arguments, e.g. name=params.string()", b.Name())
+	}
+
+	fields := starlark.StringDict{}
+	declared := map[string]bool{}
+	var problems []string
+	for _, kv := range kwargs {
+		name := string(kv[0].(starlark.String))
+		declared[name] = true
+		p, ok := kv[1].(*Param)
+		if !ok {
+			return starlark.None, fmt.Errorf("%v: expected a parameter (e.g. params.string()) for %q, got: %s", b.Name(), name, kv[1].Type())
+		}
+
+		raw, found := m.values[name]
+		switch {
+		case !found && p.required:
+			problems = append(problems, fmt.Sprintf("%v: required but not set", name))
+		case !found:
+			fields[name] = p.def
+		default:
+			v, err := p.convert(raw)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%v: %v", name, err))
+				continue
+			}
+			fields[name] = v
+		}
+	}
+
+	for name := range m.values {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("%v: unknown parameter", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return starlark.None, fmt.Errorf("%v: invalid parameters:\n- %v", b.Name(), strings.Join(problems, "\n- "))
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields), nil
+}
+
+// convert checks that the value from the values file has the parameter's type and converts it.
+func (p *Param) convert(v interface{}) (starlark.Value, error) {
+	switch p.typ {
+	case typeString:
+		if s, ok := v.(string); ok {
+			return starlark.String(s), nil
+		}
+	case typeInt:
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return starlark.MakeInt64(i), nil
+			}
+		}
+	case typeBool:
+		if b, ok := v.(bool); ok {
+			return starlark.Bool(b), nil
+		}
+	case typeDuration:
+		sv, err := toStarlark(v)
+		if err != nil {
+			return nil, err
+		}
+		d, err := host.ToDuration(sv)
+		if err != nil {
+			return nil, err
+		}
+		return host.Duration(d), nil
+	case typeList:
+		items, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		out := make([]starlark.Value, 0, len(items))
+		for i, item := range items {
+			sv, err := p.item.convert(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			out = append(out, sv)
+		}
+		return starlark.NewList(out), nil
+	case typeDict:
+		if _, ok := v.(map[string]interface{}); ok {
+			return toStarlark(v)
+		}
+	case typeAny:
+		return toStarlark(v)
+	}
+	return nil, fmt.Errorf("expected %v, got %v", p.typ, describe(v))
+}
+
+// toStarlark converts a value decoded from JSON to the equivalent starlark value.
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case string:
+		return starlark.String(t), nil
+	case bool:
+		return starlark.Bool(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return starlark.MakeInt64(i), nil
+		}
+		f, err := t.Float64()
+		return starlark.Float(f), err
+	case []interface{}:
+		out := make([]starlark.Value, 0, len(t))
+		for _, item := range t {
+			sv, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sv)
+		}
+		return starlark.NewList(out), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := starlark.NewDict(len(t))
+		for _, k := range keys {
+			sv, err := toStarlark(t[k])
+			if err != nil {
+				return nil, err
+			}
+			if err := d.SetKey(starlark.String(k), sv); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v (%T)", v, v)
+}
+
+// describe returns the type of a decoded value in the terms used by the params module.
+func describe(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", t)
+	case bool:
+		return fmt.Sprintf("bool %v", t)
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "int " + t.String()
+		}
+		return "float " + t.String()
+	case []interface{}:
+		return typeList
+	case map[string]interface{}:
+		return typeDict
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package params
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+)
+
+const testValues = `
+namespace: prod
+max_restarts: 5
+ratio: 0.75
+strict: true
+timeout: 90s
+nodes: [a, b]
+labels:
+  tier: web
+  replicas: 3
+`
+
+func TestDeclare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "values.yaml")
+	if err := ioutil.WriteFile(path, []byte(testValues), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := LoadValues(path)
+	if err != nil {
+		t.Fatalf("Failed to load values: %v", err)
+	}
+
+	all := `namespace=params.string(required=True), max_restarts=params.int(default=3), ratio=params.any(),
+	strict=params.bool(), timeout=params.duration(), nodes=params.list(item=params.string()), labels=params.dict()`
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "String", expr: `params.declare(` + all + `).namespace`, want: `"prod"`},
+		{desc: "Int", expr: `params.declare(` + all + `).max_restarts`, want: "5"},
+		{desc: "Any", expr: `params.declare(` + all + `).ratio`, want: "0.75"},
+		{desc: "Bool", expr: `params.declare(` + all + `).strict`, want: "True"},
+		{desc: "Duration", expr: `params.declare(` + all + `).timeout.seconds`, want: "90"},
+		{desc: "List", expr: `params.declare(` + all + `).nodes`, want: `["a", "b"]`},
+		{desc: "Dict", expr: `params.declare(` + all + `).labels`, want: `{"replicas": 3, "tier": "web"}`},
+		{desc: "Default when not set", expr: `params.declare(` + all + `, min_ready=params.int(default=1)).min_ready`, want: "1"},
+		{desc: "Duration default", expr: `params.declare(` + all + `, interval=params.duration(default="5m")).interval.minutes`, want: "5"},
+		{desc: "None when not set without default", expr: `params.declare(` + all + `, owner=params.string()).owner`, want: "None"},
+		{
+			desc:    "All problems reported",
+			expr:    `params.declare(namespace=params.int(), nodes=params.list(item=params.int()), owner=params.string(required=True))`,
+			wantErr: "params.declare: invalid parameters:\n- labels: unknown parameter\n- max_restarts: unknown parameter\n- namespace: expected int, got string \"prod\"\n- nodes: item 0: expected int, got string \"a\"\n- owner: required but not set\n- ratio: unknown parameter\n- strict: unknown parameter\n- timeout: unknown parameter",
+		},
+		{desc: "Float is not an int", expr: `params.declare(` + strings.Replace(all, "ratio=params.any()", "ratio=params.int()", 1) + `)`, wantErr: "- ratio: expected int, got float 0.75"},
+		{desc: "Positional arguments", expr: `params.declare(params.string())`, wantErr: "parameters must be given as keyword arguments"},
+		{desc: "Not a parameter", expr: `params.declare(namespace="prod")`, wantErr: `expected a parameter (e.g. params.string()) for "namespace", got: string`},
+		{desc: "Required with default", expr: `params.string(required=True, default="a")`, wantErr: "a required parameter can't have a default"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v, err := starlark.Eval(&starlark.Thread{}, "test", tc.expr, NewAPI(values))
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
+
+func TestLoadValues(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"values.json": `{"a": 1, "b": [true]}`,
+		"list.yaml":   "- a\n- b\n",
+		"bad.yaml":    "a: [",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if values, err := LoadValues(filepath.Join(dir, "values.json")); err != nil || len(values) != 2 {
+		t.Errorf("Expected 2 values from JSON but got %v (error: %v)", values, err)
+	}
+	if _, err := LoadValues(filepath.Join(dir, "list.yaml")); err == nil || !strings.Contains(err.Error(), "expected a map of parameter names to values") {
+		t.Errorf("Expected an error for a list of values but got %v", err)
+	}
+	if _, err := LoadValues(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), "failed to parse values file") {
+		t.Errorf("Expected a parse error but got %v", err)
+	}
+	if _, err := LoadValues(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("Expected an error for a missing file but got nil")
+	}
+	if values, err := LoadValuesIfExists(filepath.Join(dir, "missing.yaml")); err != nil || len(values) != 0 {
+		t.Errorf("Expected no values for a missing file but got %v (error: %v)", values, err)
+	}
+}
@@ -0,0 +1,321 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prom provides the prom module for running PromQL queries against the Prometheus HTTP API
+// and returning the results as starlark values.
+package prom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/sirupsen/logrus"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+const (
+	// DefaultTimeout is how long a query may take if no timeout is given.
+	DefaultTimeout = 30 * time.Second
+
+	queryPath      = "/api/v1/query"
+	queryRangePath = "/api/v1/query_range"
+
+	resultTypeVector = "vector"
+	resultTypeMatrix = "matrix"
+	resultTypeScalar = "scalar"
+	resultTypeString = "string"
+)
+
+var (
+	API = starlark.StringDict{
+		"prom": &starlarkstruct.Module{
+			Name: "prom",
+			Members: starlark.StringDict{
+				"query":       starlark.NewBuiltin("prom.query", Query),
+				"query_range": starlark.NewBuiltin("prom.query_range", QueryRange),
+			},
+		},
+	}
+)
+
+// apiResponse is the envelope of every response from the Prometheus HTTP API.
+type apiResponse struct {
+	Status    string    `json:"status"`
+	Data      queryData `json:"data"`
+	ErrorType string    `json:"errorType"`
+	Error     string    `json:"error"`
+	Warnings  []string  `json:"warnings"`
+}
+
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// series is an element of a vector (with Value) or matrix (with Values) result.
+type series struct {
+	Metric map[string]string `json:"metric"`
+	Value  samplePair        `json:"value"`
+	Values []samplePair      `json:"values"`
+}
+
+// samplePair is a [<unix time>, "<value>"] pair.
+type samplePair [2]interface{}
+
+// Query is an entry point for the `prom.query` built-in which evaluates an instant query at the given
+// time (now by default). Vectors are returned as a list of samples, each with `metric' (a dict of
+// labels), `value' and `time'. Scalars are returned as a single sample and strings as a string.
+func Query(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var addr, expr string
+	var at, timeout starlark.Value = starlark.None, starlark.None
+	headers := &starlark.Dict{}
+	unpacked := []interface{}{
+		"url", &addr,
+		"expr", &expr,
+		"time?", &at,
+		"timeout?", &timeout,
+		"headers?", &headers,
+	}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
+		return starlark.None, err
+	}
+
+	params := url.Values{"query": []string{expr}}
+	if at != starlark.None {
+		t, err := toTime(at)
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid `time' arg: %v", b.Name(), err)
+		}
+		params.Set("time", formatTime(t))
+	}
+	return doQuery(thread, b, addr, queryPath, params, timeout, headers)
+}
+
+// QueryRange is an entry point for the `prom.query_range` built-in which evaluates a query from start to
+// stop, every step. The result is a list of series, each with `metric' and `values', a list of samples.
+func QueryRange(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var addr, expr string
+	var start, stop, step starlark.Value
+	var timeout starlark.Value = starlark.None
+	headers := &starlark.Dict{}
+	unpacked := []interface{}{
+		"url", &addr,
+		"expr", &expr,
+		"start", &start,
+		"stop", &stop,
+		"step", &step,
+		"timeout?", &timeout,
+		"headers?", &headers,
+	}
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, unpacked...); err != nil {
+		return starlark.None, err
+	}
+
+	params := url.Values{"query": []string{expr}}
+	// The range is given as start and stop since `end' is a keyword in this dialect.
+	for name, v := range map[string]starlark.Value{"start": start, "stop": stop} {
+		t, err := toTime(v)
+		if err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid `%v' arg: %v", b.Name(), name, err)
+		}
+		if name == "stop" {
+			name = "end"
+		}
+		params.Set(name, formatTime(t))
+	}
+	d, err := host.ToDuration(step)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: invalid `step' arg: %v", b.Name(), err)
+	}
+	if d <= 0 {
+		return starlark.None, fmt.Errorf("%v: `step' must be positive, got %v", b.Name(), d)
+	}
+	params.Set("step", strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+	return doQuery(thread, b, addr, queryRangePath, params, timeout, headers)
+}
+
+// doQuery sends the query to the API and converts the result.
+func doQuery(thread *starlark.Thread, b *starlark.Builtin, addr, path string, params url.Values, timeoutVal starlark.Value, headers *starlark.Dict) (starlark.Value, error) {
+	timeout := DefaultTimeout
+	if timeoutVal != starlark.None {
+		var err error
+		if timeout, err = host.ToDuration(timeoutVal); err != nil {
+			return starlark.None, fmt.Errorf("%v: invalid `timeout' arg: %v", b.Name(), err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	for _, item := range headers.Items() {
+		k, kok := starlark.AsString(item[0])
+		v, vok := starlark.AsString(item[1])
+		if !kok || !vok {
+			return starlark.None, fmt.Errorf("%v: expected string keys and values for `headers' arg", b.Name())
+		}
+		req.Header.Set(k, v)
+	}
+
+	ctx, cancel := context.WithTimeout(shared.GetGoCtx(thread), timeout)
+	defer cancel()
+	logrus.Debugf("GET to %s", req.URL)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: failed to read body (response code: %d): %v", b.Name(), resp.StatusCode, err)
+	}
+	r := apiResponse{}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return starlark.None, fmt.Errorf("%v: unexpected response (response code: %d): %s", b.Name(), resp.StatusCode, raw)
+	}
+	if r.Status != "success" {
+		return starlark.None, fmt.Errorf("%v: query failed (response code: %d): %v: %v", b.Name(), resp.StatusCode, r.ErrorType, r.Error)
+	}
+	for _, w := range r.Warnings {
+		logrus.Warnf("%v: %v", b.Name(), w)
+	}
+
+	v, err := convertResult(r.Data)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%v: failed to parse %v result: %v", b.Name(), r.Data.ResultType, err)
+	}
+	return v, nil
+}
+
+func convertResult(data queryData) (starlark.Value, error) {
+	switch data.ResultType {
+	case resultTypeVector, resultTypeMatrix:
+		var result []series
+		if err := json.Unmarshal(data.Result, &result); err != nil {
+			return nil, err
+		}
+		out := make([]starlark.Value, 0, len(result))
+		for _, s := range result {
+			v, err := convertSeries(data.ResultType, s)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return starlark.NewList(out), nil
+	case resultTypeScalar, resultTypeString:
+		var pair samplePair
+		if err := json.Unmarshal(data.Result, &pair); err != nil {
+			return nil, err
+		}
+		if data.ResultType == resultTypeString {
+			s, _ := pair[1].(string)
+			return starlark.String(s), nil
+		}
+		return newSample(nil, pair)
+	}
+	return nil, fmt.Errorf("unknown result type")
+}
+
+func convertSeries(resultType string, s series) (starlark.Value, error) {
+	if resultType == resultTypeVector {
+		return newSample(s.Metric, s.Value)
+	}
+	values := make([]starlark.Value, 0, len(s.Values))
+	for _, pair := range s.Values {
+		v, err := newSample(nil, pair)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"metric": labelsDict(s.Metric),
+		"values": starlark.NewList(values),
+	}), nil
+}
+
+// newSample returns a struct with the value and time of the pair, along with the labels of the metric
+// if given.
+func newSample(metric map[string]string, pair samplePair) (starlark.Value, error) {
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected a timestamp, got %v", pair[0])
+	}
+	s, ok := pair[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string value, got %v", pair[1])
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := starlark.StringDict{
+		"value": starlark.Float(v),
+		"time":  host.Time(time.Unix(0, int64(ts*float64(time.Second))).UTC()),
+	}
+	if metric != nil {
+		fields["metric"] = labelsDict(metric)
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, fields), nil
+}
+
+func labelsDict(labels map[string]string) *starlark.Dict {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := starlark.NewDict(len(labels))
+	for _, k := range keys {
+		d.SetKey(starlark.String(k), starlark.String(labels[k]))
+	}
+	return d
+}
+
+// toTime converts a time.time, an RFC 3339 string or a number of seconds since the epoch to a time.
+func toTime(v starlark.Value) (time.Time, error) {
+	switch t := v.(type) {
+	case host.Time:
+		return time.Time(t), nil
+	case starlark.String:
+		return time.Parse(time.RFC3339, string(t))
+	case starlark.Int:
+		s, ok := t.Int64()
+		if !ok {
+			return time.Time{}, fmt.Errorf("time out of range: %v", t)
+		}
+		return time.Unix(s, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("expected a time.time, RFC 3339 string or unix time, got: %s", v.Type())
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', -1, 64)
+}
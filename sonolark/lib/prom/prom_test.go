@@ -0,0 +1,114 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/host"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+// fakePrometheus returns canned results based on the query, echoing the time parameters back in the
+// results so that tests can check they were sent.
+func fakePrometheus() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Header.Get("Authorization") == "Bearer bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "unauthorized")
+			return
+		}
+		switch {
+		case r.URL.Path == queryPath && q.Get("query") == "up":
+			ts := q.Get("time")
+			if ts == "" {
+				ts = "1646370367"
+			}
+			fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [
+				{"metric": {"__name__": "up", "job": "apiserver"}, "value": [%v, "1"]},
+				{"metric": {"__name__": "up", "job": "kubelet"}, "value": [%v, "0"]}]}}`, ts, ts)
+		case r.URL.Path == queryPath && q.Get("query") == "scalar(1.5)":
+			fmt.Fprint(w, `{"status": "success", "data": {"resultType": "scalar", "result": [1646370367.5, "1.5"]}}`)
+		case r.URL.Path == queryPath && q.Get("query") == `"hello"`:
+			fmt.Fprint(w, `{"status": "success", "warnings": ["be careful"], "data": {"resultType": "string", "result": [1646370367, "hello"]}}`)
+		case r.URL.Path == queryRangePath && q.Get("step") == "30":
+			fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "matrix", "result": [
+				{"metric": {"job": "apiserver"}, "values": [[%v, "1"], [%v, "NaN"]]}]}}`, q.Get("start"), q.Get("end"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status": "error", "errorType": "bad_data", "error": "parse error"}`)
+		}
+	}))
+}
+
+func TestQuery(t *testing.T) {
+	s := fakePrometheus()
+	defer s.Close()
+	predeclared := starlark.StringDict{
+		"prom": API["prom"],
+		"time": host.TimeAPI["time"],
+		"url":  starlark.String(s.URL),
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		expr    string
+		want    string
+		wantErr string
+	}{
+		{desc: "Vector", expr: `[(s.metric["job"], s.value) for s in prom.query(url, "up")]`, want: `[("apiserver", 1), ("kubelet", 0)]`},
+		{desc: "Vector values compare with ints", expr: `[s.metric["job"] for s in prom.query(url, "up") if s.value < 1]`, want: `["kubelet"]`},
+		{desc: "Sample time", expr: `prom.query(url, "up")[0].time`, want: "2022-03-04T05:06:07Z"},
+		{desc: "Query at time", expr: `prom.query(url, "up", time=time.parse("2022-01-01T00:00:00Z"))[0].time`, want: "2022-01-01T00:00:00Z"},
+		{desc: "Query at unix time", expr: `prom.query(url, "up", time=1640995200)[0].time.year`, want: "2022"},
+		{desc: "Scalar", expr: `prom.query(url, "scalar(1.5)")`, want: "struct(time = 2022-03-04T05:06:07.5Z, value = 1.5)"},
+		{desc: "String", expr: `prom.query(url, '"hello"')`, want: `"hello"`},
+		{desc: "Matrix", expr: `prom.query_range(url, "up", start="2022-01-01T00:00:00Z", stop="2022-01-01T00:01:00Z", step="30s")`,
+			want: `[struct(metric = {"job": "apiserver"}, values = [struct(time = 2022-01-01T00:00:00Z, value = 1), struct(time = 2022-01-01T00:01:00Z, value = NaN)])]`},
+		{desc: "Query error", expr: `prom.query(url, "up{")`, wantErr: "prom.query: query failed (response code: 400): bad_data: parse error"},
+		{desc: "Not Prometheus", expr: `prom.query(url, "up", headers={"Authorization": "Bearer bad"})`, wantErr: "unexpected response (response code: 401): unauthorized"},
+		{desc: "Invalid time", expr: `prom.query(url, "up", time="yesterday")`, wantErr: "invalid `time' arg"},
+		{desc: "Invalid step", expr: `prom.query_range(url, "up", start=0, stop=60, step="0s")`, wantErr: "`step' must be positive"},
+		{desc: "Unreachable", expr: `prom.query("http://127.0.0.1:1", "up", timeout="1s")`, wantErr: "prom.query: Get"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			thread := &starlark.Thread{Name: t.Name()}
+			shared.SetGoCtx(thread, context.Background())
+			v, err := starlark.Eval(thread, "test", tc.expr, predeclared)
+			switch {
+			case err != nil && len(tc.wantErr) == 0:
+				t.Fatalf("Unexpected error: %v", err)
+			case err == nil && len(tc.wantErr) > 0:
+				t.Fatalf("Expected error %q but got nil", tc.wantErr)
+			case err != nil && !strings.Contains(err.Error(), tc.wantErr):
+				t.Fatalf("Expected error %q but got %q", tc.wantErr, err.Error())
+			case err != nil:
+				return
+			}
+			if v.String() != tc.want {
+				t.Errorf("Expected %v but got %v", tc.want, v.String())
+			}
+		})
+	}
+}
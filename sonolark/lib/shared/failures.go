@@ -0,0 +1,61 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"github.com/k14s/starlark-go/starlark"
+)
+
+const (
+	// FailureDetailsKey is the thread local key for the structured details of failures (e.g. failed
+	// assertions) which should be reported with the current test.
+	FailureDetailsKey = "failure_details"
+)
+
+// AddFailureDetails records structured information about a failure against the thread so that
+// it can be included in the results of the current test.
+func AddFailureDetails(thread *starlark.Thread, details map[string]interface{}) {
+	existing, _ := thread.Local(FailureDetailsKey).([]map[string]interface{})
+	thread.SetLocal(FailureDetailsKey, append(existing, details))
+}
+
+// TakeFailureDetails returns the failure details recorded against the thread and clears them.
+func TakeFailureDetails(thread *starlark.Thread) []map[string]interface{} {
+	existing, _ := thread.Local(FailureDetailsKey).([]map[string]interface{})
+	thread.SetLocal(FailureDetailsKey, nil)
+	return existing
+}
+
+const (
+	// SoftFailuresKey is the thread local key for the messages of soft assertions (e.g. check.equals)
+	// which failed during the current test without stopping it.
+	SoftFailuresKey = "soft_failures"
+)
+
+// AddSoftFailure records the message of a failed soft assertion against the thread. The current
+// test is failed with all of the recorded messages once it completes.
+func AddSoftFailure(thread *starlark.Thread, msg string) {
+	existing, _ := thread.Local(SoftFailuresKey).([]string)
+	thread.SetLocal(SoftFailuresKey, append(existing, msg))
+}
+
+// TakeSoftFailures returns the soft failure messages recorded against the thread and clears them.
+func TakeSoftFailures(thread *starlark.Thread) []string {
+	existing, _ := thread.Local(SoftFailuresKey).([]string)
+	thread.SetLocal(SoftFailuresKey, nil)
+	return existing
+}
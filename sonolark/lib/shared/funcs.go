@@ -40,3 +40,15 @@ func SetGoCtxWithValues(thread *starlark.Thread, keyValPairs ...interface{}) {
 	}
 	SetGoCtx(thread, ctx)
 }
+
+// NewChildThread returns a thread which can be used to run starlark code concurrently with the
+// parent. It shares the parent's print and load behavior and starts with a copy of its Go context.
+func NewChildThread(parent *starlark.Thread, name string) *starlark.Thread {
+	child := &starlark.Thread{
+		Name:  name,
+		Print: parent.Print,
+		Load:  parent.Load,
+	}
+	SetGoCtx(child, GetGoCtx(parent))
+	return child
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+)
+
+const (
+	// CancelReasonCtxKey is the context key for a human readable reason explaining why the
+	// context was cancelled, e.g. which timeout expired.
+	CancelReasonCtxKey = "sonolarkCancelReason"
+
+	// StepBudgetCtxKey is the context key for the *StepBudget shared by all threads of a script.
+	StepBudgetCtxKey = "sonolarkStepBudget"
+)
+
+// StepBudget limits the number of steps a script may take. The starlark interpreter we use does
+// not count instructions so each call to a library builtin counts as a single step.
+type StepBudget struct {
+	Max  uint64
+	used uint64
+}
+
+// SetGoCtxTimeout gives the thread's Go context a deadline d from now. The reason is reported by
+// Step if the deadline is exceeded. If the context already has an earlier deadline, the reason for
+// that deadline is kept. Callers must call the returned func to release resources.
+func SetGoCtxTimeout(thread *starlark.Thread, d time.Duration, reason string) context.CancelFunc {
+	ctx := GetGoCtx(thread)
+	deadline := time.Now().Add(d)
+	if existing, ok := ctx.Deadline(); ok && existing.Before(deadline) {
+		return func() {}
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	SetGoCtx(thread, context.WithValue(ctx, CancelReasonCtxKey, reason))
+	return cancel
+}
+
+// CancelReason returns why the context was cancelled or the empty string if it was not.
+func CancelReason(ctx context.Context) string {
+	if ctx.Err() == nil {
+		return ""
+	}
+	if reason, ok := ctx.Value(CancelReasonCtxKey).(string); ok && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return reason
+	}
+	return ctx.Err().Error()
+}
+
+// Step counts a single step against the script's budget and returns an error if the budget is exhausted
+// or the thread's Go context has been cancelled (e.g. it timed out). Builtins call it before doing any
+// work so that scripts stop at the next opportunity once they exceed their limits. A thread without a
+// Go context has no limits.
+func Step(thread *starlark.Thread) error {
+	ctx, ok := thread.Local(GoCtxKey).(context.Context)
+	if !ok {
+		return nil
+	}
+	if reason := CancelReason(ctx); len(reason) > 0 {
+		return errors.New(reason)
+	}
+	if budget, ok := ctx.Value(StepBudgetCtxKey).(*StepBudget); ok && budget.Max > 0 {
+		if atomic.AddUint64(&budget.used, 1) > budget.Max {
+			return fmt.Errorf("script exceeded its budget of %v steps", budget.Max)
+		}
+	}
+	return nil
+}
+
+// GuardBuiltin wraps the builtin so that it calls Step before being called. Since the interpreter
+// can't be interrupted, builtin calls are the points at which a cancelled script stops.
+func GuardBuiltin(b *starlark.Builtin) *starlark.Builtin {
+	return starlark.NewBuiltin(b.Name(), func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := Step(thread); err != nil {
+			return starlark.None, fmt.Errorf("%v: %v", b.Name(), err)
+		}
+		return b.CallInternal(thread, args, kwargs)
+	})
+}
+
+// GuardModule returns a copy of the module whose builtins are wrapped by GuardBuiltin. Modules which
+// return other modules or objects with builtins at runtime must guard those as they are built.
+func GuardModule(m *starlarkstruct.Module) *starlarkstruct.Module {
+	guarded := &starlarkstruct.Module{Name: m.Name, Members: starlark.StringDict{}}
+	for name, member := range m.Members {
+		if b, ok := member.(*starlark.Builtin); ok {
+			member = GuardBuiltin(b)
+		}
+		guarded.Members[name] = member
+	}
+	return guarded
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+)
+
+func TestStep(t *testing.T) {
+	t.Run("Budget is shared across threads", func(t *testing.T) {
+		parent := &starlark.Thread{}
+		SetGoCtx(parent, context.Background())
+		SetGoCtxWithValues(parent, StepBudgetCtxKey, &StepBudget{Max: 2})
+		child := NewChildThread(parent, "child")
+
+		if err := Step(parent); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := Step(child); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err := Step(parent)
+		if err == nil || err.Error() != "script exceeded its budget of 2 steps" {
+			t.Errorf("Expected budget error but got %v", err)
+		}
+	})
+
+	t.Run("Earliest deadline reason is reported", func(t *testing.T) {
+		thread := &starlark.Thread{}
+		SetGoCtx(thread, context.Background())
+		cancelOuter := SetGoCtxTimeout(thread, time.Millisecond, "outer")
+		defer cancelOuter()
+		cancelInner := SetGoCtxTimeout(thread, time.Hour, "inner")
+		defer cancelInner()
+
+		<-GetGoCtx(thread).Done()
+		err := Step(thread)
+		if err == nil || err.Error() != "outer" {
+			t.Errorf("Expected error %q but got %v", "outer", err)
+		}
+	})
+
+	t.Run("Explicit cancellation", func(t *testing.T) {
+		thread := &starlark.Thread{}
+		ctx, cancel := context.WithCancel(context.Background())
+		SetGoCtx(thread, ctx)
+		if err := Step(thread); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		cancel()
+		err := Step(thread)
+		if err == nil || err.Error() != context.Canceled.Error() {
+			t.Errorf("Expected error %q but got %v", context.Canceled, err)
+		}
+	})
+}
+
+func TestGuardModule(t *testing.T) {
+	calls := 0
+	m := GuardModule(&starlarkstruct.Module{Name: "m", Members: starlark.StringDict{
+		"fn": starlark.NewBuiltin("m.fn", func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+			calls++
+			return starlark.None, nil
+		}),
+		"value": starlark.MakeInt(1),
+	}})
+
+	thread := &starlark.Thread{}
+	SetGoCtx(thread, context.Background())
+	SetGoCtxWithValues(thread, StepBudgetCtxKey, &StepBudget{Max: 1})
+	if _, err := starlark.Call(thread, m.Members["fn"], nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := starlark.Call(thread, m.Members["fn"], nil, nil)
+	if err == nil || err.Error() != "m.fn: script exceeded its budget of 1 steps" {
+		t.Errorf("Expected budget error but got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the builtin to be called once but got %v", calls)
+	}
+	if m.Members["value"] != starlark.MakeInt(1) {
+		t.Errorf("Expected other members to be kept but got %v", m.Members["value"])
+	}
+
+	if _, err := starlark.Call(&starlark.Thread{}, m.Members["fn"], nil, nil); err != nil {
+		t.Errorf("Expected a thread without a Go context to have no limits but got %v", err)
+	}
+}
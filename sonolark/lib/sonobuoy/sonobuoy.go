@@ -20,6 +20,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/starlark-go/starlarkstruct"
@@ -30,17 +34,26 @@ import (
 )
 
 const (
-	EnvKeySonobuoy          = "SONOBUOY"
-	EnvKeySonobuoyConfigDir = "SONOBUOY_CONFIG_DIR"
+	EnvKeySonobuoy           = "SONOBUOY"
+	EnvKeySonobuoyConfigDir  = "SONOBUOY_CONFIG_DIR"
+	EnvKeySonobuoyResultsDir = sono.SonobuoyResultsDirKey
 
 	WriterCtxKey         = "sonoWriter"
 	ProgressWriterCtxKey = "sonoProgressWriter"
 	CurrentTestCtxKey    = "sonoCurrentTest"
+	ReporterCtxKey       = "sonoReporter"
+	TestTimeoutCtxKey    = "sonoTestTimeout"
+
+	preTestCtxKey    = "sonoPreTestCtx"
+	testCancelCtxKey = "sonoTestCancel"
 
 	testStatusPassed  = "passed"
 	testStatusFailed  = "failed"
 	testStatusSkipped = "skipped"
 	testStatusError   = "error"
+
+	// detailsFailures is the key in the test details under which structured failure details are reported.
+	detailsFailures = "failures"
 )
 
 var (
@@ -83,6 +96,7 @@ func StartSuite(thread *starlark.Thread, count int64) {
 	shared.SetGoCtxWithValues(thread,
 		WriterCtxKey, &w,
 		ProgressWriterCtxKey, &pw,
+		ReporterCtxKey, &reporter{running: map[*starlark.Thread]string{}},
 	)
 }
 
@@ -96,11 +110,46 @@ func (b sonobuoyModule) StartTest(thread *starlark.Thread, f *starlark.Builtin,
 		return starlark.None, err
 	}
 
-	_, _, pw := getSonobuoyHelpers(thread)
+	StartTest(thread, testName)
+	return starlark.None, nil
+}
+
+// StartTest marks the test as running on the thread. If a per-test timeout is set in the Go context, the
+// context is given a deadline until the test completes. Any test already running on the thread is failed.
+func StartTest(thread *starlark.Thread, testName string) {
+	FailRunningTest(thread, fmt.Sprintf("test %q was started before this test completed", testName))
+	// Discard failures which were not recorded against a test.
+	shared.TakeFailureDetails(thread)
+	shared.TakeSoftFailures(thread)
+
+	ctx, _, pw := getSonobuoyHelpers(thread)
+	r := getReporter(ctx)
+	r.Lock()
 	pw.StartTest(testName)
+	r.running[thread] = testName
+	r.Unlock()
+
+	thread.SetLocal(preTestCtxKey, ctx)
+	if d, ok := ctx.Value(TestTimeoutCtxKey).(time.Duration); ok && d > 0 {
+		cancel := shared.SetGoCtxTimeout(thread, d, fmt.Sprintf("test %q exceeded its timeout of %v", testName, d))
+		thread.SetLocal(testCancelCtxKey, cancel)
+	}
 	shared.SetGoCtxWithValues(thread, CurrentTestCtxKey, testName)
+}
 
-	return starlark.None, nil
+// endTest restores the Go context from before the current test was started, releasing any timeout.
+func endTest(thread *starlark.Thread) {
+	if cancel, ok := thread.Local(testCancelCtxKey).(context.CancelFunc); ok {
+		cancel()
+		thread.SetLocal(testCancelCtxKey, nil)
+	}
+	if ctx, ok := thread.Local(preTestCtxKey).(context.Context); ok {
+		shared.SetGoCtx(thread, ctx)
+		thread.SetLocal(preTestCtxKey, nil)
+	}
+
+	// Clear out current test.
+	shared.SetGoCtxWithValues(thread, CurrentTestCtxKey, "")
 }
 
 func markTestComplete(thread *starlark.Thread, failed, skipped bool, err error, msg string) {
@@ -114,6 +163,44 @@ func markTestComplete(thread *starlark.Thread, failed, skipped bool, err error,
 		logrus.Warnf("Attempting to mark current test as complete (failed=%v skipped=%v err=%v msg=%v) but there is no currently executing test.", failed, skipped, err, msg)
 		return
 	}
+
+	r := getReporter(ctx)
+	r.Lock()
+	defer r.Unlock()
+	defer endTest(thread)
+	if r.running[thread] != testName {
+		logrus.Warnf("Attempting to mark test %q as complete (failed=%v skipped=%v err=%v msg=%v) but it was already completed.", testName, failed, skipped, err, msg)
+		return
+	}
+	delete(r.running, thread)
+
+	// Failed soft assertions fail the test regardless of how it was completed.
+	if soft := shared.TakeSoftFailures(thread); len(soft) > 0 {
+		failed, skipped = true, false
+		msg = softFailureMessage(msg, soft)
+		if err == nil {
+			err = fmt.Errorf("%v check(s) failed", len(soft))
+		}
+	}
+	recordTest(w, pw, testName, failed, skipped, err, msg, shared.TakeFailureDetails(thread))
+}
+
+// softFailureMessage appends the messages of the failed soft assertions to the test message.
+func softFailureMessage(msg string, soft []string) string {
+	var sb strings.Builder
+	if len(msg) > 0 {
+		sb.WriteString(msg)
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "%v check(s) failed:", len(soft))
+	for _, m := range soft {
+		sb.WriteString("\n- ")
+		sb.WriteString(m)
+	}
+	return sb.String()
+}
+
+func recordTest(w *sono.SonobuoyResultsWriter, pw *sono.ProgressReporter, testName string, failed, skipped bool, err error, msg string, failures []map[string]interface{}) {
 	pw.StopTest(testName, failed, skipped, err)
 	result := testStatusPassed
 	switch {
@@ -126,9 +213,13 @@ func markTestComplete(thread *starlark.Thread, failed, skipped bool, err error,
 	}
 
 	w.AddTest(testName, result, err, msg)
-
-	// Clear out current test.
-	shared.SetGoCtxWithValues(thread, CurrentTestCtxKey, "")
+	if failed && len(failures) > 0 {
+		item := &w.Data.Items[len(w.Data.Items)-1]
+		if item.Details == nil {
+			item.Details = map[string]interface{}{}
+		}
+		item.Details[detailsFailures] = failures
+	}
 }
 
 func (b sonobuoyModule) SkipTest(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
@@ -178,10 +269,14 @@ func (b sonobuoyModule) PassTest(thread *starlark.Thread, f *starlark.Builtin, a
 		}
 	}
 
-	markTestComplete(thread, false, false, nil, msg)
+	PassTest(thread, msg)
 	return starlark.None, nil
 }
 
+func PassTest(thread *starlark.Thread, msg string) {
+	markTestComplete(thread, false, false, nil, msg)
+}
+
 func (b sonobuoyModule) update(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	if args.Len() != 1 {
 		return starlark.None, fmt.Errorf("expected 1 argument: progress message for Sonobuoy")
@@ -192,7 +287,10 @@ func (b sonobuoyModule) update(thread *starlark.Thread, f *starlark.Builtin, arg
 		return starlark.None, err
 	}
 
-	_, _, pw := getSonobuoyHelpers(thread)
+	ctx, _, pw := getSonobuoyHelpers(thread)
+	r := getReporter(ctx)
+	r.Lock()
+	defer r.Unlock()
 	pw.SendMessage(msg)
 
 	return starlark.None, nil
@@ -208,26 +306,71 @@ func (b sonobuoyModule) Done(thread *starlark.Thread, f *starlark.Builtin, args
 
 func Done(thread *starlark.Thread) {
 	logrus.Trace("sonobuoy.Done called")
-	ctx, w, pw := getSonobuoyHelpers(thread)
-	maybeTest := ctx.Value(CurrentTestCtxKey)
-	testName := ""
-	if maybeTest != nil {
-		testName = maybeTest.(string)
+	// Complete the test on this thread first so that its soft failures are reported.
+	FailRunningTest(thread, "suite completed while test still running")
+	Finish(shared.GetGoCtx(thread), "suite completed while test still running")
+}
+
+// Finish fails any tests still running (on any thread) with the given reason and writes the results.
+// It only relies on the Go context of the suite rather than a thread so it is safe to call while the
+// script is still executing, e.g. once it has timed out.
+func Finish(ctx context.Context, reason string) {
+	w, pw := helpersFromCtx(ctx)
+	r := getReporter(ctx)
+	r.Lock()
+	defer r.Unlock()
+
+	var running []string
+	for thread, testName := range r.running {
+		running = append(running, testName)
+		delete(r.running, thread)
 	}
-	if len(testName) > 0 {
+	sort.Strings(running)
+	for _, testName := range running {
 		logrus.Tracef("Found test %q still marked as currently running. Marking it as failed.", testName)
-		markTestComplete(thread, true, false, errors.New("suite completed while test still running"), "suite completed while test still running")
+		recordTest(w, pw, testName, true, false, errors.New(reason), reason, nil)
 	}
+
 	pw.SendMessage("Suite completed.")
 	w.Done(true)
 }
 
+// FailRunningTest marks the test currently running on the thread (if any) as failed with the given reason.
+func FailRunningTest(thread *starlark.Thread, reason string) {
+	if testName := CurrentTest(thread); len(testName) > 0 {
+		logrus.Tracef("Found test %q still marked as currently running. Marking it as failed.", testName)
+		markTestComplete(thread, true, false, errors.New(reason), reason)
+	}
+}
+
+// CurrentTest returns the name of the test currently running on the thread or the empty string if there is none.
+func CurrentTest(thread *starlark.Thread) string {
+	testName, _ := shared.GetGoCtx(thread).Value(CurrentTestCtxKey).(string)
+	return testName
+}
+
 func getSonobuoyHelpers(thread *starlark.Thread) (context.Context, *sono.SonobuoyResultsWriter, *sono.ProgressReporter) {
 	ctx := shared.GetGoCtx(thread)
+	w, pw := helpersFromCtx(ctx)
+	return ctx, w, pw
+}
+
+func helpersFromCtx(ctx context.Context) (*sono.SonobuoyResultsWriter, *sono.ProgressReporter) {
 	w := ctx.Value(WriterCtxKey).(*sono.SonobuoyResultsWriter)
 	pw := ctx.Value(ProgressWriterCtxKey).(*sono.ProgressReporter)
+	return w, pw
+}
 
-	return ctx, w, pw
+// reporter guards the results and progress writers so that tests can be reported from concurrently
+// executing threads. It also tracks the test running on each thread so that they can be failed from
+// outside of the thread.
+type reporter struct {
+	sync.Mutex
+	running map[*starlark.Thread]string
+}
+
+func getReporter(ctx context.Context) *reporter {
+	return ctx.Value(ReporterCtxKey).(*reporter)
 }
 
 func RunningViaSonobuoy(env map[string]string) bool {
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 the Sonobuoy Project contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sonobuoy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	sono "github.com/vmware-tanzu/sonobuoy-plugins/plugin-helper"
+	"github.com/vmware-tanzu/sonobuoy-plugins/sonolark/lib/shared"
+)
+
+func TestTestTimeout(t *testing.T) {
+	thread := &starlark.Thread{}
+	shared.SetGoCtx(thread, context.Background())
+	shared.SetGoCtxWithValues(thread, TestTimeoutCtxKey, 10*time.Millisecond)
+	StartSuite(thread, -1)
+	suiteCtx := shared.GetGoCtx(thread)
+
+	StartTest(thread, "slow")
+	<-shared.GetGoCtx(thread).Done()
+	err := shared.Step(thread)
+	if err == nil || err.Error() != `test "slow" exceeded its timeout of 10ms` {
+		t.Fatalf("Expected test timeout error but got %v", err)
+	}
+	if suiteCtx.Err() != nil {
+		t.Fatalf("Expected the suite context to be unaffected by the test timeout but got %v", suiteCtx.Err())
+	}
+	FailTest(thread, err.Error())
+
+	if _, ok := shared.GetGoCtx(thread).Deadline(); ok || CurrentTest(thread) != "" {
+		t.Fatalf("Expected the suite context to be restored once the test completed")
+	}
+	if err := shared.Step(thread); err != nil {
+		t.Fatalf("Unexpected error after the test completed: %v", err)
+	}
+
+	StartTest(thread, "fast")
+	if err := shared.Step(thread); err != nil {
+		t.Fatalf("Expected the next test to get its own timeout but got %v", err)
+	}
+	PassTest(thread, "")
+
+	w := suiteCtx.Value(WriterCtxKey).(*sono.SonobuoyResultsWriter)
+	if len(w.Data.Items) != 2 || w.Data.Items[0].Status != testStatusFailed || w.Data.Items[1].Status != testStatusPassed {
+		t.Errorf("Unexpected results: %+v", w.Data.Items)
+	}
+}